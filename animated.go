@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/discord/lilliput"
+)
+
+// Limits for animated images, anything past them gets cut off
+const (
+	maxAnimatedFrameRate = 30
+	maxAnimatedDuration  = time.Second * 10
+	maxAnimatedFrames    = 300
+)
+
+// Whether an image has more than one frame
+func isAnimatedImage(imageBytes []byte, mime string) bool {
+	switch mime {
+	case "image/webp":
+		return isAnimatedWebP(imageBytes)
+	case "image/png":
+		return isAnimatedPNG(imageBytes)
+	case "image/gif":
+		lilliputDecoder, err := lilliput.NewDecoder(imageBytes)
+		if err != nil {
+			return false
+		}
+		defer lilliputDecoder.Close()
+		lilliputHeader, err := lilliputDecoder.Header()
+		if err != nil {
+			return false
+		}
+		return lilliputHeader.IsAnimated()
+	default:
+		return false
+	}
+}
+
+// Whether a WebP has the animation flag set in its extended header
+func isAnimatedWebP(imageBytes []byte) bool {
+	return len(imageBytes) > 20 &&
+		string(imageBytes[0:4]) == "RIFF" &&
+		string(imageBytes[8:12]) == "WEBP" &&
+		string(imageBytes[12:16]) == "VP8X" &&
+		imageBytes[20]&0x02 != 0
+}
+
+// Whether a PNG has an animation control chunk before its image data (APNG)
+func isAnimatedPNG(imageBytes []byte) bool {
+	if len(imageBytes) < 8 || string(imageBytes[0:8]) != "\x89PNG\r\n\x1a\n" {
+		return false
+	}
+	for i := 8; i+8 <= len(imageBytes); {
+		chunkLength := int(binary.BigEndian.Uint32(imageBytes[i : i+4]))
+		switch string(imageBytes[i+4 : i+8]) {
+		case "acTL":
+			return true
+		case "IDAT", "IEND":
+			return false
+		}
+		if chunkLength < 0 || chunkLength > len(imageBytes) {
+			return false
+		}
+		i += 12 + chunkLength // length + type + data + CRC
+	}
+	return false
+}
+
+// Resize an animated image to fit within maxSize and convert it to an animated WebP,
+// dropping frames past the frame rate, duration, and frame count limits.
+func transcodeAnimatedImage(imageBytes []byte, mime string, maxSize int) ([]byte, error) {
+	switch mime {
+	case "image/gif", "image/png":
+		inputFormat := "gif"
+		if mime == "image/png" {
+			inputFormat = "apng"
+		}
+		return runCommand(
+			time.Second*60,
+			imageBytes,
+			"ffmpeg",
+			"-hide_banner",
+			"-loglevel", "error",
+			"-f", inputFormat,
+			"-i", "pipe:0",
+			"-t", strconv.FormatFloat(maxAnimatedDuration.Seconds(), 'f', -1, 64),
+			"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:flags=lanczos", maxSize, maxSize),
+			"-fpsmax", strconv.Itoa(maxAnimatedFrameRate),
+			"-frames:v", strconv.Itoa(maxAnimatedFrames),
+			"-c:v", "libwebp_anim",
+			"-quality", "80",
+			"-loop", "0",
+			"-f", "webp",
+			"pipe:1",
+		)
+	case "image/webp":
+		// FFmpeg can't decode animated WebPs, so only the frame count is limited here
+		return runCommand(
+			time.Second*60,
+			imageBytes,
+			"convert",
+			fmt.Sprintf("webp:-[0-%d]", maxAnimatedFrames-1),
+			"-coalesce",
+			"-resize", fmt.Sprintf("%dx%d", maxSize, maxSize),
+			"-layers", "Optimize",
+			"-quality", "80",
+			"webp:-",
+		)
+	default:
+		return nil, ErrUnsupportedFile
+	}
+}
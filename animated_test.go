@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// Build a PNG out of chunks (with dummy CRCs)
+func testPNG(chunks ...string) []byte {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	for _, chunk := range chunks {
+		chunkType, data := chunk[:4], chunk[4:]
+		png = binary.BigEndian.AppendUint32(png, uint32(len(data)))
+		png = append(png, chunkType...)
+		png = append(png, data...)
+		png = append(png, 0, 0, 0, 0)
+	}
+	return png
+}
+
+func TestIsAnimatedPNG(t *testing.T) {
+	ihdr := "IHDR" + string(make([]byte, 13))
+	actl := "acTL" + string(make([]byte, 8))
+
+	tests := []struct {
+		name       string
+		imageBytes []byte
+		want       bool
+	}{
+		{"static PNG", testPNG(ihdr, "IDAT\x00", "IEND"), false},
+		{"APNG", testPNG(ihdr, actl, "IDAT\x00", "IEND"), true},
+		{"APNG with chunks before acTL", testPNG(ihdr, "sRGB\x00", "tEXtComment\x00hi", actl, "IDAT\x00"), true},
+		{"acTL after the image data", testPNG(ihdr, "IDAT\x00", actl, "IEND"), false},
+		{"truncated", testPNG(ihdr)[:20], false},
+		{"chunk length past the end", append([]byte("\x89PNG\r\n\x1a\n"), "\x00\x00\x03\xe8IHDR"...), false},
+		{"huge chunk length", append([]byte("\x89PNG\r\n\x1a\n"), "\xff\xff\xff\xffIHDR"...), false},
+		{"not a PNG", []byte("GIF89a"), false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAnimatedPNG(tt.imageBytes); got != tt.want {
+				t.Errorf("isAnimatedPNG() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAnimatedWebP(t *testing.T) {
+	// RIFF header, VP8X chunk header, then the VP8X flags byte
+	webp := func(chunk string, flags byte) []byte {
+		return append([]byte("RIFF\x00\x00\x00\x00WEBP"+chunk+"\x0a\x00\x00\x00"), flags, 0, 0, 0)
+	}
+
+	tests := []struct {
+		name       string
+		imageBytes []byte
+		want       bool
+	}{
+		{"animated", webp("VP8X", 0x02), true},
+		{"animated with alpha", webp("VP8X", 0x12), true},
+		{"extended but not animated", webp("VP8X", 0x10), false},
+		{"lossy", webp("VP8 ", 0x02), false},
+		{"lossless", webp("VP8L", 0x02), false},
+		{"truncated", webp("VP8X", 0x02)[:20], false},
+		{"not a WebP", []byte("RIFF\x00\x00\x00\x00WAVEfmt \x00\x00\x00\x00\x02"), false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAnimatedWebP(tt.imageBytes); got != tt.want {
+				t.Errorf("isAnimatedWebP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
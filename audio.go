@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Number of points in audio waveforms
+const waveformLength = 100
+
+// Sample rate audio gets decoded at to generate waveforms
+const waveformSampleRate = 8000
+
+type AudioMetadata struct {
+	Duration   float64 `bson:"duration" json:"duration"` // seconds
+	Codec      string  `bson:"codec" json:"codec"`
+	Bitrate    int64   `bson:"bitrate,omitempty" json:"bitrate,omitempty"` // bits per second
+	SampleRate int     `bson:"sample_rate" json:"sample_rate"`
+	Channels   int     `bson:"channels" json:"channels"`
+	Waveform   []int   `bson:"waveform,omitempty" json:"waveform,omitempty"` // peaks from 0-255
+}
+
+// Output of ffprobe that we care about
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName  string `json:"codec_name"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+		BitRate    string `json:"bit_rate"`
+		Width      int    `json:"width"`
+		Height     int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// Get the duration, codec, bitrate, sample rate, channels, and waveform of an audio file
+func getAudioMetadata(fileBytes []byte) (*AudioMetadata, error) {
+	// FFmpeg needs to be able to seek in some audio containers, so it can't read from stdin
+	tmpPath, err := createTempFile(fileBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath)
+
+	// Probe audio stream
+	probeBytes, err := runCommand(
+		time.Second*30,
+		nil,
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-select_streams", "a:0",
+		tmpPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var probe ffprobeOutput
+	if err := json.Unmarshal(probeBytes, &probe); err != nil {
+		return nil, err
+	}
+	if len(probe.Streams) == 0 {
+		return nil, ErrUnsupportedFile
+	}
+	stream := probe.Streams[0]
+
+	// Create metadata
+	metadata := &AudioMetadata{
+		Codec:    stream.CodecName,
+		Channels: stream.Channels,
+	}
+	metadata.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	metadata.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+	metadata.Bitrate, _ = strconv.ParseInt(stream.BitRate, 10, 64)
+	if metadata.Bitrate == 0 {
+		metadata.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	}
+
+	// Decode audio as mono 16-bit PCM
+	pcmBytes, err := runCommand(
+		time.Second*60,
+		nil,
+		"ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", tmpPath,
+		"-map", "0:a:0",
+		"-ac", "1",
+		"-ar", strconv.Itoa(waveformSampleRate),
+		"-f", "s16le",
+		"pipe:1",
+	)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Waveform = getWaveform(pcmBytes, waveformLength)
+
+	return metadata, nil
+}
+
+// Downsample mono 16-bit little-endian PCM into the peaks of a number of points,
+// scaled so the loudest point is 255.
+func getWaveform(pcmBytes []byte, length int) []int {
+	samples := len(pcmBytes) / 2
+	if samples == 0 {
+		return nil
+	}
+	length = min(length, samples)
+
+	// Get peaks
+	peaks := make([]float64, length)
+	var maxPeak float64
+	for i := 0; i < samples; i++ {
+		sample := math.Abs(float64(int16(binary.LittleEndian.Uint16(pcmBytes[i*2:]))))
+		point := i * length / samples
+		peaks[point] = math.Max(peaks[point], sample)
+		maxPeak = math.Max(maxPeak, sample)
+	}
+
+	// Scale peaks
+	waveform := make([]int, length)
+	if maxPeak == 0 {
+		return waveform
+	}
+	for i, peak := range peaks {
+		waveform[i] = int(math.Round(peak / maxPeak * 255))
+	}
+	return waveform
+}
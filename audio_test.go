@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Encode samples as 16-bit little-endian PCM
+func testPCM(samples ...int16) []byte {
+	var pcm []byte
+	for _, sample := range samples {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+	}
+	return pcm
+}
+
+func TestGetWaveform(t *testing.T) {
+	tests := []struct {
+		name     string
+		pcmBytes []byte
+		length   int
+		want     []int
+	}{
+		{"no samples", nil, 4, nil},
+		{"odd byte", []byte{0x01}, 4, nil},
+		{"silence", testPCM(0, 0, 0, 0), 2, []int{0, 0}},
+		{"one sample per point", testPCM(100, -200, 50, 0), 4, []int{128, 255, 64, 0}},
+		{"peaks of each point", testPCM(10, -100, 20, 50, 0, 0, 100, 5), 4, []int{255, 128, 0, 255}},
+		{"negative peak", testPCM(-32768, 16384), 2, []int{255, 128}},
+		{"fewer samples than points", testPCM(100, 200), 10, []int{128, 255}},
+		{"trailing odd byte", append(testPCM(100, 200), 0xff), 2, []int{128, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getWaveform(tt.pcmBytes, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getWaveform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
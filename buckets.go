@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Bucket previews of files get cached in
+const previewsBucket = "attachment-previews"
+
+type BucketConfig struct {
+	Name           string   `json:"name"`
+	MaxSizeMib     int64    `json:"max_size_mib"`
+	AllowedMimes   []string `json:"allowed_mimes,omitempty"`    // any MIME type is allowed if empty
+	OptimizeSize   int      `json:"optimize_size,omitempty"`    // images are stored as uploaded if 0
+	OptimizeFormat string   `json:"optimize_format,omitempty"`  // webp (default), png, or jpeg
+	AspectRatio    []int    `json:"aspect_ratio,omitempty"`     // e.g. [3, 1], crops are cover-cropped to it if set
+	AutoCrop       bool     `json:"auto_crop,omitempty"`        // whether uploads without a crop get cover-cropped to the aspect ratio too
+	AllowCrop      bool     `json:"allow_crop,omitempty"`       // whether uploads can choose a crop rectangle and focal point
+	PreviewSize    int      `json:"preview_size,omitempty"`     // previews are disabled if 0
+	Transcode      bool     `json:"transcode,omitempty"`        // whether videos get a web-safe MP4 rendition
+	HLSMinDuration int      `json:"hls_min_duration,omitempty"` // transcoded videos at least this many seconds long also get packaged for HLS, disabled if 0
+	Private        bool     `json:"private,omitempty"`          // only the uploader can download private files
+	RetentionDays  int      `json:"retention_days,omitempty"`   // files are deleted this many days after being uploaded, even if they're still referenced, and are kept forever if 0
+}
+
+var bucketNameRegex = regexp.MustCompile(`^[a-z0-9\-]{3,63}$`)
+
+var buckets = map[string]*BucketConfig{}
+var bucketOrder = []string{}
+
+// Load the bucket registry from the file at BUCKETS_CONFIG,
+// or fall back to the default buckets if it's not set.
+func loadBuckets() error {
+	var configs []*BucketConfig
+	if os.Getenv("BUCKETS_CONFIG") != "" {
+		data, err := os.ReadFile(os.Getenv("BUCKETS_CONFIG"))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return err
+		}
+	} else {
+		configs = defaultBuckets()
+	}
+
+	for _, config := range configs {
+		if !bucketNameRegex.MatchString(config.Name) || config.Name == previewsBucket || config.Name == "data-exports" {
+			return errors.New("invalid bucket name: " + config.Name)
+		}
+		if buckets[config.Name] != nil {
+			return errors.New("duplicate bucket: " + config.Name)
+		}
+		if err := config.validate(); err != nil {
+			return err
+		}
+		buckets[config.Name] = config
+		bucketOrder = append(bucketOrder, config.Name)
+	}
+
+	return nil
+}
+
+// The buckets that existed before the registry, with limits from the MAX_*_SIZE_MIB env vars
+func defaultBuckets() []*BucketConfig {
+	maxIconSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_ICON_SIZE_MIB"), 10, 32)
+	maxEmojiSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_EMOJI_SIZE_MIB"), 10, 32)
+	maxStickerSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_STICKER_SIZE_MIB"), 10, 32)
+	maxAttachmentSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_ATTACHMENT_SIZE_MIB"), 10, 32)
+	maxBannerSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_BANNER_SIZE_MIB"), 10, 32)
+	maxChatBackgroundSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_CHAT_BACKGROUND_SIZE_MIB"), 10, 32)
+	images := []string{"image/png", "image/jpeg", "image/webp", "image/gif", "image/heic", "image/heif", "image/tiff"}
+	return []*BucketConfig{
+		{Name: "icons", MaxSizeMib: maxIconSizeMib, AllowedMimes: images, OptimizeSize: 256, AspectRatio: []int{1, 1}, AllowCrop: true},
+		{Name: "emojis", MaxSizeMib: maxEmojiSizeMib, AllowedMimes: images, OptimizeSize: 128, AspectRatio: []int{1, 1}, AllowCrop: true},
+		{Name: "stickers", MaxSizeMib: maxStickerSizeMib, AllowedMimes: images, OptimizeSize: 384},
+		{Name: "attachments", MaxSizeMib: maxAttachmentSizeMib, PreviewSize: 720, Transcode: true, HLSMinDuration: 60},
+		{Name: "banners", MaxSizeMib: maxBannerSizeMib, AllowedMimes: images, OptimizeSize: 1500, AspectRatio: []int{3, 1}, AutoCrop: true, AllowCrop: true},
+		{Name: "chat-backgrounds", MaxSizeMib: maxChatBackgroundSizeMib, AllowedMimes: images, OptimizeSize: 1920, AspectRatio: []int{16, 9}, AutoCrop: true, AllowCrop: true},
+	}
+}
+
+// Make sure every bucket exists in the local MinIO region
+func ensureBuckets() error {
+	s3Client := s3Clients[s3RegionOrder[0]]
+	for _, name := range append(bucketOrder, previewsBucket) {
+		exists, err := s3Client.BucketExists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := s3Client.MakeBucket(ctx, name, minio.MakeBucketOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get the chi route pattern that matches any bucket name
+func bucketRoutePattern() string {
+	names := make([]string, len(bucketOrder))
+	for i, name := range bucketOrder {
+		names[i] = regexp.QuoteMeta(name)
+	}
+	return "{bucket:" + strings.Join(names, "|") + "}"
+}
+
+// Catch bucket settings that would otherwise only fail at upload time
+func (b *BucketConfig) validate() error {
+	if b.MaxSizeMib <= 0 {
+		return errors.New("max_size_mib must be positive in bucket: " + b.Name)
+	}
+	switch b.OptimizeFormat {
+	case "", "webp", "png", "jpeg":
+	default:
+		return errors.New("unknown optimize_format " + strconv.Quote(b.OptimizeFormat) + " in bucket: " + b.Name)
+	}
+	if b.AspectRatio != nil && (len(b.AspectRatio) != 2 || b.AspectRatio[0] <= 0 || b.AspectRatio[1] <= 0) {
+		return errors.New("aspect_ratio must be two positive numbers in bucket: " + b.Name)
+	}
+	if b.OptimizeSize < 0 || b.PreviewSize < 0 || b.HLSMinDuration < 0 || b.RetentionDays < 0 {
+		return errors.New("optimize_size, preview_size, hls_min_duration, and retention_days can't be negative in bucket: " + b.Name)
+	}
+	return nil
+}
+
+func (b *BucketConfig) MaxSize() int64 {
+	return b.MaxSizeMib << 20
+}
+
+func (b *BucketConfig) AllowsMime(mime string) bool {
+	if len(b.AllowedMimes) == 0 {
+		return true
+	}
+	for _, allowedMime := range b.AllowedMimes {
+		if allowedMime == mime {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestValidateBucketConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BucketConfig
+		wantErr bool
+	}{
+		{"minimal", BucketConfig{Name: "files", MaxSizeMib: 1}, false},
+		{"fully configured", BucketConfig{Name: "banners", MaxSizeMib: 5, OptimizeSize: 1500, OptimizeFormat: "jpeg", AspectRatio: []int{3, 1}, AutoCrop: true, PreviewSize: 720, HLSMinDuration: 60, RetentionDays: 30}, false},
+		{"zero max size", BucketConfig{Name: "files"}, true},
+		{"negative max size", BucketConfig{Name: "files", MaxSizeMib: -1}, true},
+		{"unknown optimize format", BucketConfig{Name: "files", MaxSizeMib: 1, OptimizeFormat: "gif"}, true},
+		{"aspect ratio with one number", BucketConfig{Name: "files", MaxSizeMib: 1, AspectRatio: []int{3}}, true},
+		{"aspect ratio with three numbers", BucketConfig{Name: "files", MaxSizeMib: 1, AspectRatio: []int{3, 1, 1}}, true},
+		{"empty aspect ratio", BucketConfig{Name: "files", MaxSizeMib: 1, AspectRatio: []int{}}, true},
+		{"zero aspect ratio", BucketConfig{Name: "files", MaxSizeMib: 1, AspectRatio: []int{16, 0}}, true},
+		{"negative aspect ratio", BucketConfig{Name: "files", MaxSizeMib: 1, AspectRatio: []int{-1, 1}}, true},
+		{"negative optimize size", BucketConfig{Name: "files", MaxSizeMib: 1, OptimizeSize: -1}, true},
+		{"negative retention", BucketConfig{Name: "files", MaxSizeMib: 1, RetentionDays: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Run an external command (e.g. ImageMagick or FFmpeg) with stdin as its input.
+// Returns everything it wrote to stdout.
+func runCommand(timeout time.Duration, stdin []byte, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(name + ": " + err.Error() + ": " + msg)
+		}
+		return nil, errors.New(name + ": " + err.Error())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// Write data to a temporary file for commands that need to seek in their input.
+// The caller is responsible for removing it.
+func createTempFile(data []byte) (string, error) {
+	tmpFile, err := os.CreateTemp("", "meower-uploads-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Images browsers can't display, with their ImageMagick format names.
+// They get converted to PNG before going through the rest of the image pipeline.
+var convertibleImages = map[string]string{
+	"image/heic": "heic",
+	"image/heif": "heif",
+	"image/tiff": "tiff",
+}
+
+// Other MIME types clients send for convertible images
+var convertibleImageAliases = map[string]string{
+	"image/heic-sequence": "image/heic",
+	"image/heif-sequence": "image/heif",
+	"image/tif":           "image/tiff",
+	"image/x-tiff":        "image/tiff",
+}
+
+// Get the MIME type clients mean for convertible images they send under another name
+func normaliseImageMime(mime string) string {
+	if alias := convertibleImageAliases[strings.ToLower(mime)]; alias != "" {
+		return alias
+	}
+	return mime
+}
+
+// Get the MIME type of a file from its contents.
+// Images are always identified by their contents, since they often get uploaded as
+// application/octet-stream and the declared type can't be trusted for them.
+// Files that claim to be images but aren't become application/octet-stream
+// (never something browsers would run, like HTML), and other declared types are kept.
+func sniffImageMime(fileBytes []byte, mime string) string {
+	mime = normaliseImageMime(mime)
+
+	// Images
+	if imageMime := detectImageMime(fileBytes); imageMime != "" {
+		return imageMime
+	}
+
+	// Files claiming to be images that aren't
+	if strings.HasPrefix(strings.ToLower(mime), "image/") {
+		return "application/octet-stream"
+	}
+
+	return mime
+}
+
+// Get the MIME type of an image from its contents, or an empty string if it isn't one
+func detectImageMime(fileBytes []byte) string {
+	// HEIC/HEIF/AVIF (ISO base media file with an image brand)
+	if len(fileBytes) >= 12 && string(fileBytes[4:8]) == "ftyp" {
+		switch string(fileBytes[8:12]) {
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return "image/heic"
+		case "mif1", "msf1":
+			return "image/heif"
+		case "avif", "avis":
+			return "image/avif"
+		}
+	}
+
+	// TIFF (little or big endian)
+	if bytes.HasPrefix(fileBytes, []byte("II*\x00")) || bytes.HasPrefix(fileBytes, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+
+	// Everything Go knows about (PNG, JPEG, GIF, WebP, BMP, ICO)
+	if mime := http.DetectContentType(fileBytes); strings.HasPrefix(mime, "image/") {
+		return mime
+	}
+
+	return ""
+}
+
+// Convert the first image of a HEIC/HEIF or TIFF file to PNG
+func convertToPNG(imageBytes []byte, mime string) ([]byte, error) {
+	format := convertibleImages[mime]
+	if format == "" {
+		return nil, ErrUnsupportedFile
+	}
+
+	return runCommand(
+		time.Second*60,
+		imageBytes,
+		"convert",
+		format+":-[0]",
+		"-auto-orient",
+		"png:-",
+	)
+}
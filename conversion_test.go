@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestSniffImageMime(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	gif := []byte("GIF89a\x01\x00\x01\x00")
+	webp := []byte("RIFF\x1a\x00\x00\x00WEBPVP8 ")
+	heic := []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00")
+	heif := []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00")
+	avif := []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00")
+	tiffLE := []byte("II*\x00\x08\x00\x00\x00")
+	tiffBE := []byte("MM\x00*\x00\x00\x00\x08")
+	mp4 := []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	html := []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+	text := []byte("just some text")
+
+	tests := []struct {
+		name      string
+		fileBytes []byte
+		mime      string
+		want      string
+	}{
+		{"PNG", png, "image/png", "image/png"},
+		{"PNG without a type", png, "", "image/png"},
+		{"PNG as octet-stream", png, "application/octet-stream", "image/png"},
+		{"JPEG claiming to be a PNG", jpeg, "image/png", "image/jpeg"},
+		{"GIF", gif, "image/gif", "image/gif"},
+		{"WebP", webp, "image/webp", "image/webp"},
+		{"HEIC as octet-stream", heic, "application/octet-stream", "image/heic"},
+		{"HEIC sequence alias", heic, "image/heic-sequence", "image/heic"},
+		{"HEIF", heif, "", "image/heif"},
+		{"AVIF", avif, "image/avif", "image/avif"},
+		{"little-endian TIFF", tiffLE, "image/tif", "image/tiff"},
+		{"big-endian TIFF", tiffBE, "", "image/tiff"},
+		{"HTML claiming to be a PNG", html, "image/png", "application/octet-stream"},
+		{"HTML claiming to be an SVG", html, "image/svg+xml", "application/octet-stream"},
+		{"text claiming to be a JPEG", text, "image/jpeg", "application/octet-stream"},
+		{"HTML as octet-stream", html, "application/octet-stream", "application/octet-stream"},
+		{"video keeps its declared type", mp4, "video/quicktime", "video/quicktime"},
+		{"JSON keeps its declared type", []byte(`{"a":1}`), "application/json", "application/json"},
+		{"text without a type", text, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffImageMime(tt.fileBytes, tt.mime); got != tt.want {
+				t.Errorf("sniffImageMime(%q) = %q, want %q", tt.mime, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormaliseImageMime(t *testing.T) {
+	tests := []struct {
+		mime string
+		want string
+	}{
+		{"image/heic-sequence", "image/heic"},
+		{"image/HEIF-Sequence", "image/heif"},
+		{"image/x-tiff", "image/tiff"},
+		{"image/png", "image/png"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mime, func(t *testing.T) {
+			if got := normaliseImageMime(tt.mime); got != tt.want {
+				t.Errorf("normaliseImageMime(%q) = %q, want %q", tt.mime, got, tt.want)
+			}
+		})
+	}
+}
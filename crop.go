@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Crop requested by the uploader, all values are optional
+type CropParams struct {
+	HasRect bool
+	X       int
+	Y       int
+	Width   int
+	Height  int
+
+	HasFocalPoint bool
+	FocalX        float64 // 0-1, relative to the width of the image
+	FocalY        float64 // 0-1, relative to the height of the image
+}
+
+// Crop that was applied to an image, in pixels of the original image
+type ImageCrop struct {
+	X      int      `bson:"x" json:"x"`
+	Y      int      `bson:"y" json:"y"`
+	Width  int      `bson:"width" json:"width"`
+	Height int      `bson:"height" json:"height"`
+	FocalX *float64 `bson:"focal_x,omitempty" json:"focal_x,omitempty"`
+	FocalY *float64 `bson:"focal_y,omitempty" json:"focal_y,omitempty"`
+	Custom bool     `bson:"custom,omitempty" json:"custom,omitempty"` // whether the uploader chose the crop
+}
+
+// Work out the area of an image to keep.
+// Starts from the requested crop rectangle (or the whole image), then cover-crops it
+// to the bucket's aspect ratio around the focal point (or the centre of the rectangle).
+// Returns nil if the whole image should be kept.
+func getImageCrop(width int, height int, params *CropParams, aspectRatio []int) (*ImageCrop, error) {
+	crop := &ImageCrop{Width: width, Height: height}
+
+	// Requested rectangle
+	if params != nil && params.HasRect {
+		if params.X < 0 || params.Y < 0 || params.Width <= 0 || params.Height <= 0 ||
+			params.X+params.Width > width || params.Y+params.Height > height {
+			return nil, ErrInvalidCrop
+		}
+		crop.X, crop.Y, crop.Width, crop.Height = params.X, params.Y, params.Width, params.Height
+		crop.Custom = true
+	}
+
+	// Aspect ratio
+	if len(aspectRatio) == 2 && aspectRatio[0] > 0 && aspectRatio[1] > 0 {
+		ratio := float64(aspectRatio[0]) / float64(aspectRatio[1])
+		newWidth, newHeight := crop.Width, crop.Height
+		if float64(crop.Width)/float64(crop.Height) > ratio {
+			newWidth = max(1, int(math.Round(float64(crop.Height)*ratio)))
+		} else {
+			newHeight = max(1, int(math.Round(float64(crop.Width)/ratio)))
+		}
+
+		// Centre the crop on the focal point, without going outside the rectangle
+		centerX := float64(crop.X) + float64(crop.Width)/2
+		centerY := float64(crop.Y) + float64(crop.Height)/2
+		if params != nil && params.HasFocalPoint {
+			if params.FocalX < 0 || params.FocalX > 1 || params.FocalY < 0 || params.FocalY > 1 {
+				return nil, ErrInvalidCrop
+			}
+			centerX = params.FocalX * float64(width)
+			centerY = params.FocalY * float64(height)
+			crop.FocalX, crop.FocalY = &params.FocalX, &params.FocalY
+			crop.Custom = true
+		}
+		crop.X = clampInt(int(math.Round(centerX-float64(newWidth)/2)), crop.X, crop.X+crop.Width-newWidth)
+		crop.Y = clampInt(int(math.Round(centerY-float64(newHeight)/2)), crop.Y, crop.Y+crop.Height-newHeight)
+		crop.Width, crop.Height = newWidth, newHeight
+	}
+
+	if crop.X == 0 && crop.Y == 0 && crop.Width == width && crop.Height == height {
+		return nil, nil
+	}
+	return crop, nil
+}
+
+// Crop every frame of an image
+func cropImage(imageBytes []byte, mime string, crop *ImageCrop) ([]byte, error) {
+	format := imageMagickFormats[mime]
+	if format == "" {
+		return nil, ErrUnsupportedFile
+	}
+
+	// ImageMagick only reads the first frame of animated PNGs
+	if mime == "image/png" && isAnimatedPNG(imageBytes) {
+		return runCommand(
+			time.Second*60,
+			imageBytes,
+			"ffmpeg",
+			"-hide_banner",
+			"-loglevel", "error",
+			"-f", "apng",
+			"-i", "pipe:0",
+			"-vf", fmt.Sprintf("crop=%d:%d:%d:%d", crop.Width, crop.Height, crop.X, crop.Y),
+			"-plays", "0",
+			"-f", "apng",
+			"pipe:1",
+		)
+	}
+
+	return runCommand(
+		time.Second*30,
+		imageBytes,
+		"convert",
+		format+":-",
+		"-auto-orient",
+		"-coalesce",
+		"-crop", fmt.Sprintf("%dx%d+%d+%d", crop.Width, crop.Height, crop.X, crop.Y),
+		"+repage",
+		format+":-",
+	)
+}
+
+func clampInt(n int, lower int, upper int) int {
+	return max(lower, min(n, upper))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGetImageCrop(t *testing.T) {
+	tests := []struct {
+		name        string
+		width       int
+		height      int
+		params      *CropParams
+		aspectRatio []int
+		want        *ImageCrop
+		wantFocal   bool
+		wantErr     error
+	}{
+		{
+			name:   "whole image",
+			width:  300,
+			height: 200,
+		},
+		{
+			name:        "already the right aspect ratio",
+			width:       300,
+			height:      100,
+			aspectRatio: []int{3, 1},
+		},
+		{
+			name:        "centred cover crop",
+			width:       300,
+			height:      300,
+			aspectRatio: []int{3, 1},
+			want:        &ImageCrop{X: 0, Y: 100, Width: 300, Height: 100},
+		},
+		{
+			name:   "rectangle",
+			width:  300,
+			height: 200,
+			params: &CropParams{HasRect: true, X: 10, Y: 20, Width: 100, Height: 50},
+			want:   &ImageCrop{X: 10, Y: 20, Width: 100, Height: 50, Custom: true},
+		},
+		{
+			name:        "rectangle cover cropped to aspect ratio",
+			width:       300,
+			height:      200,
+			params:      &CropParams{HasRect: true, X: 0, Y: 0, Width: 200, Height: 200},
+			aspectRatio: []int{2, 1},
+			want:        &ImageCrop{X: 0, Y: 50, Width: 200, Height: 100, Custom: true},
+		},
+		{
+			name:    "rectangle outside the image",
+			width:   300,
+			height:  200,
+			params:  &CropParams{HasRect: true, X: 250, Y: 0, Width: 100, Height: 100},
+			wantErr: ErrInvalidCrop,
+		},
+		{
+			name:    "empty rectangle",
+			width:   300,
+			height:  200,
+			params:  &CropParams{HasRect: true, X: 0, Y: 0, Width: 0, Height: 100},
+			wantErr: ErrInvalidCrop,
+		},
+		{
+			name:        "focal point at the left edge",
+			width:       400,
+			height:      100,
+			params:      &CropParams{HasFocalPoint: true, FocalX: 0, FocalY: 0.5},
+			aspectRatio: []int{1, 1},
+			want:        &ImageCrop{X: 0, Y: 0, Width: 100, Height: 100, Custom: true},
+			wantFocal:   true,
+		},
+		{
+			name:        "focal point at the right edge",
+			width:       400,
+			height:      100,
+			params:      &CropParams{HasFocalPoint: true, FocalX: 1, FocalY: 0.5},
+			aspectRatio: []int{1, 1},
+			want:        &ImageCrop{X: 300, Y: 0, Width: 100, Height: 100, Custom: true},
+			wantFocal:   true,
+		},
+		{
+			name:        "focal point outside the image",
+			width:       400,
+			height:      100,
+			params:      &CropParams{HasFocalPoint: true, FocalX: 1.5, FocalY: 0.5},
+			aspectRatio: []int{1, 1},
+			wantErr:     ErrInvalidCrop,
+		},
+		{
+			name:      "focal point without an aspect ratio",
+			width:     400,
+			height:    100,
+			params:    &CropParams{HasFocalPoint: true, FocalX: 0.2, FocalY: 0.5},
+			wantFocal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crop, err := getImageCrop(tt.width, tt.height, tt.params, tt.aspectRatio)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if crop != nil {
+					t.Fatalf("got crop %+v, want nil", *crop)
+				}
+				return
+			}
+			if crop == nil {
+				t.Fatalf("got nil crop, want %+v", *tt.want)
+			}
+			if crop.X != tt.want.X || crop.Y != tt.want.Y || crop.Width != tt.want.Width ||
+				crop.Height != tt.want.Height || crop.Custom != tt.want.Custom {
+				t.Errorf("got crop %+v, want %+v", *crop, *tt.want)
+			}
+			if hasFocal := crop.FocalX != nil && crop.FocalY != nil; hasFocal != tt.wantFocal {
+				t.Errorf("got focal point %v, want %v", hasFocal, tt.wantFocal)
+			}
+		})
+	}
+}
+
+func TestParseCropParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    url.Values
+		want    *CropParams
+		wantErr error
+	}{
+		{
+			name: "no crop",
+			form: url.Values{},
+		},
+		{
+			name: "rectangle",
+			form: url.Values{"crop_x": {"1"}, "crop_y": {"2"}, "crop_width": {"30"}, "crop_height": {"40"}},
+			want: &CropParams{HasRect: true, X: 1, Y: 2, Width: 30, Height: 40},
+		},
+		{
+			name:    "rectangle without a position",
+			form:    url.Values{"crop_width": {"30"}, "crop_height": {"40"}},
+			wantErr: ErrInvalidCrop,
+		},
+		{
+			name:    "rectangle that isn't a number",
+			form:    url.Values{"crop_x": {"1"}, "crop_y": {"2"}, "crop_width": {"wide"}, "crop_height": {"40"}},
+			wantErr: ErrInvalidCrop,
+		},
+		{
+			name: "focal point",
+			form: url.Values{"focal_x": {"0.25"}, "focal_y": {"0.75"}},
+			want: &CropParams{HasFocalPoint: true, FocalX: 0.25, FocalY: 0.75},
+		},
+		{
+			name:    "half a focal point",
+			form:    url.Values{"focal_x": {"0.25"}},
+			wantErr: ErrInvalidCrop,
+		},
+		{
+			name: "rectangle and focal point",
+			form: url.Values{
+				"crop_x": {"0"}, "crop_y": {"0"}, "crop_width": {"10"}, "crop_height": {"10"},
+				"focal_x": {"0.5"}, "focal_y": {"0.5"},
+			},
+			want: &CropParams{HasRect: true, Width: 10, Height: 10, HasFocalPoint: true, FocalX: 0.5, FocalY: 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			params, err := parseCropParams(r)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if params != nil {
+					t.Fatalf("got params %+v, want nil", *params)
+				}
+				return
+			}
+			if params == nil || *params != *tt.want {
+				t.Errorf("got params %+v, want %+v", params, *tt.want)
+			}
+		})
+	}
+}
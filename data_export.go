@@ -0,0 +1,432 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Redis channel data export progress gets published to
+const dataExportsChannel = "data_exports"
+
+// Redis list data export jobs are queued in
+const dataExportQueue = "data_export_jobs"
+
+// Redis sorted set of pending and running data exports, scored by when they were last updated
+const dataExportsInFlight = "data_exports_in_flight"
+
+// How long a data export can go without reporting progress before it's queued again
+const dataExportStaleAfter = time.Minute * 30
+
+// How long cancelled data exports stay cancelled for, longer than any export could still be running
+const dataExportCancelledTTL = time.Hour * 24 * 7
+
+// How often running data exports report their progress
+const (
+	dataExportReportInterval = time.Second * 2
+	dataExportReportFiles    = 50
+)
+
+type DataExportStatus struct {
+	Id          string `json:"id"`
+	UserId      string `json:"user_id"`
+	Status      string `json:"status"` // pending, running, completed, failed
+	FilesDone   int    `json:"files_done"`
+	FilesTotal  int    `json:"files_total"`
+	Size        int64  `json:"size,omitempty"`
+	UpdatedAt   int64  `json:"updated_at"`
+	CompletedAt int64  `json:"completed_at,omitempty"`
+}
+
+type dataExportManifestEntry struct {
+	File
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Queue generating a data export of every file uploaded by a user.
+// Returns the ID of the export, progress is reported via Redis.
+// If the user already has an export pending or running, the ID of that export is returned instead.
+func CreateDataExport(userId string) (string, error) {
+	id, err := generateId()
+	if err != nil {
+		return "", err
+	}
+
+	// Only allow one export per user at a time
+	claimed, err := rdb.SetNX(ctx, "data_export_user:"+userId, id, time.Hour*24).Result()
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		existingId, err := rdb.Get(ctx, "data_export_user:"+userId).Result()
+		if err == nil {
+			return existingId, nil
+		} else if err != redis.Nil {
+			return "", err
+		}
+		return CreateDataExport(userId) // finished in the meantime
+	}
+
+	// Release the lock (and forget the export) if it doesn't get queued,
+	// so the user isn't stuck with an export that's never going to run
+	queued := false
+	defer func() {
+		if queued {
+			return
+		}
+		if err := rdb.Del(ctx, "data_export_user:"+userId, "data_export:"+id).Err(); err != nil {
+			sentry.CaptureException(err)
+		}
+		if err := rdb.ZRem(ctx, dataExportsInFlight, id).Err(); err != nil {
+			sentry.CaptureException(err)
+		}
+	}()
+
+	status := DataExportStatus{
+		Id:     id,
+		UserId: userId,
+		Status: "pending",
+	}
+	if err := status.report(); err != nil {
+		return "", err
+	}
+	if err := rdb.LPush(ctx, dataExportQueue, id).Err(); err != nil {
+		return "", err
+	}
+	queued = true
+
+	return id, nil
+}
+
+// Get the status of a data export
+func getDataExportStatus(id string) (*DataExportStatus, error) {
+	encoded, err := rdb.Get(ctx, "data_export:"+id).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var status DataExportStatus
+	return &status, json.Unmarshal(encoded, &status)
+}
+
+// Whether the export has been cancelled (e.g. by its user's files being purged)
+func (s *DataExportStatus) cancelled() (bool, error) {
+	count, err := rdb.Exists(ctx, "data_export_cancelled:"+s.Id).Result()
+	return count > 0, err
+}
+
+// Save the export status to Redis and publish it for the main server.
+// Returns ErrDataExportCancelled instead if the export has been cancelled.
+func (s *DataExportStatus) report() error {
+	if cancelled, err := s.cancelled(); err != nil {
+		return err
+	} else if cancelled {
+		return ErrDataExportCancelled
+	}
+
+	s.UpdatedAt = time.Now().Unix()
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := rdb.Set(ctx, "data_export:"+s.Id, encoded, time.Hour*24*7).Err(); err != nil {
+		return err
+	}
+
+	// Keep track of unfinished exports so lost ones can be queued again
+	if s.Status == "pending" || s.Status == "running" {
+		err = rdb.ZAdd(ctx, dataExportsInFlight, redis.Z{Score: float64(s.UpdatedAt), Member: s.Id}).Err()
+	} else {
+		err = rdb.ZRem(ctx, dataExportsInFlight, s.Id).Err()
+		if err == nil {
+			err = rdb.Del(ctx, "data_export_user:"+s.UserId).Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return rdb.Publish(ctx, dataExportsChannel, encoded).Err()
+}
+
+// Take jobs off the data export queue forever
+func runDataExportWorker() {
+	for {
+		result, err := rdb.BRPop(ctx, 0, dataExportQueue).Result()
+		if err != nil {
+			sentry.CaptureException(err)
+			time.Sleep(time.Second * 5)
+			continue
+		}
+
+		status, err := getDataExportStatus(result[1])
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if err := status.run(); err == ErrDataExportCancelled {
+			continue
+		} else if err != nil {
+			sentry.CaptureException(err)
+			status.Status = "failed"
+			if err := status.report(); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+	}
+}
+
+// Queue data exports again if they haven't reported progress in a while (e.g. from a worker restarting)
+func requeueStaleDataExports() error {
+	ids, err := rdb.ZRangeByScore(ctx, dataExportsInFlight, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Add(-dataExportStaleAfter).Unix(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		status, err := getDataExportStatus(id)
+		if err == redis.Nil {
+			// Status expired, so there's nothing left to run
+			if err := rdb.ZRem(ctx, dataExportsInFlight, id).Err(); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		status.Status = "pending"
+		status.FilesDone = 0
+		if err := status.report(); err != nil {
+			return err
+		}
+		if err := rdb.LPush(ctx, dataExportQueue, id).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete every data export of a user from every region, along with their statuses.
+// Unfinished exports get cancelled first, so workers that are running them throw them away.
+// Returns the IDs of the deleted exports.
+func purgeUserDataExports(userId string) ([]string, error) {
+	var ids []string
+	deletedIds := make(map[string]bool)
+
+	// Cancel exports and delete their statuses (including exports that haven't finished yet)
+	iter := rdb.Scan(ctx, 0, "data_export:*", 100).Iterator()
+	for iter.Next(ctx) {
+		id := strings.TrimPrefix(iter.Val(), "data_export:")
+		status, err := getDataExportStatus(id)
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return ids, err
+		}
+		if status.UserId != userId {
+			continue
+		}
+		if err := rdb.Set(ctx, "data_export_cancelled:"+id, "1", dataExportCancelledTTL).Err(); err != nil {
+			return ids, err
+		}
+		if err := rdb.Del(ctx, "data_export:"+id).Err(); err != nil {
+			return ids, err
+		}
+		if err := rdb.ZRem(ctx, dataExportsInFlight, id).Err(); err != nil {
+			return ids, err
+		}
+		deletedIds[id] = true
+		ids = append(ids, id)
+	}
+	if err := iter.Err(); err != nil {
+		return ids, err
+	}
+
+	// Objects
+	for _, s3Client := range s3Clients {
+		for objInfo := range s3Client.ListObjects(ctx, "data-exports", minio.ListObjectsOptions{}) {
+			if objInfo.Err != nil {
+				return ids, objInfo.Err
+			}
+			statInfo, err := s3Client.StatObject(ctx, "data-exports", objInfo.Key, minio.StatObjectOptions{})
+			if err != nil {
+				return ids, err
+			}
+			if statInfo.UserMetadata["User-Id"] != userId {
+				continue
+			}
+			if err := s3Client.RemoveObject(ctx, "data-exports", objInfo.Key, minio.RemoveObjectOptions{}); err != nil {
+				return ids, err
+			}
+			if !deletedIds[objInfo.Key] {
+				deletedIds[objInfo.Key] = true
+				ids = append(ids, objInfo.Key)
+			}
+		}
+	}
+
+	// Delete statuses again in case a worker reported progress just before being cancelled
+	for _, id := range ids {
+		if err := rdb.Del(ctx, "data_export:"+id).Err(); err != nil {
+			return ids, err
+		}
+	}
+
+	return ids, rdb.Del(ctx, "data_export_user:"+userId).Err()
+}
+
+func (s *DataExportStatus) run() error {
+	// Get files
+	cur, err := db.Collection("files").Find(context.TODO(), bson.M{"uploaded_by": s.UserId})
+	if err != nil {
+		return err
+	}
+	var files []File
+	if err := cur.All(context.TODO(), &files); err != nil {
+		return err
+	}
+
+	s.Status = "running"
+	s.FilesTotal = len(files)
+	if err := s.report(); err != nil {
+		return err
+	}
+
+	// Stream the zip straight into the data exports bucket
+	if cancelled, err := s.cancelled(); err != nil {
+		return err
+	} else if cancelled {
+		return ErrDataExportCancelled
+	}
+	pr, pw := io.Pipe()
+	type putResult struct {
+		info minio.UploadInfo
+		err  error
+	}
+	uploaded := make(chan putResult, 1)
+	go func() {
+		info, err := s3Clients[s3RegionOrder[0]].PutObject(
+			ctx,
+			"data-exports",
+			s.Id,
+			pr,
+			-1,
+			minio.PutObjectOptions{
+				ContentType:  "application/zip",
+				UserMetadata: map[string]string{"User-Id": s.UserId},
+			},
+		)
+		pr.CloseWithError(err)
+		uploaded <- putResult{info, err}
+	}()
+
+	err = s.writeZip(pw, files)
+	pw.CloseWithError(err)
+	upload := <-uploaded
+	if err == nil {
+		err = upload.err
+	}
+	if err != nil {
+		return err
+	}
+
+	// Throw the export away if it got cancelled while it was being uploaded
+	cancelled, err := s.cancelled()
+	if err != nil || cancelled {
+		if removeErr := s3Clients[s3RegionOrder[0]].RemoveObject(ctx, "data-exports", s.Id, minio.RemoveObjectOptions{}); removeErr != nil {
+			sentry.CaptureException(removeErr)
+		}
+		if err != nil {
+			return err
+		}
+		return ErrDataExportCancelled
+	}
+
+	s.Size = upload.info.Size
+	s.Status = "completed"
+	s.CompletedAt = time.Now().Unix()
+	return s.report()
+}
+
+func (s *DataExportStatus) writeZip(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+
+	// Add files
+	manifest := make([]dataExportManifestEntry, 0, len(files))
+	lastReport := time.Now()
+	for _, f := range files {
+		entry := dataExportManifestEntry{File: f}
+		path, err := addFileToZip(zw, &f)
+		if err != nil {
+			entry.Error = "failed to get file"
+		} else {
+			entry.Path = path
+		}
+		manifest = append(manifest, entry)
+
+		// Report progress every so often
+		s.FilesDone++
+		if s.FilesDone%dataExportReportFiles == 0 || time.Since(lastReport) >= dataExportReportInterval {
+			lastReport = time.Now()
+			if err := s.report(); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+	}
+
+	// Add manifest
+	manifestWriter, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Copy a file's object into the zip, returns the path within the zip
+func addFileToZip(zw *zip.Writer, f *File) (string, error) {
+	obj, _, err := f.GetObject()
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	filename := f.Filename
+	if filename == "" {
+		filename = f.Id
+	}
+	path := fmt.Sprintf("%s/%s_%s", f.Bucket, f.Id, filename)
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     path,
+		Method:   zip.Deflate,
+		Modified: time.Unix(f.UploadedAt, 0),
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(fw, obj); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Maximum size of PDFs and text files that get rendered into previews
+const maxDocumentPreviewSize = 50 << 20
+
+// Maximum number of lines and columns of text files that get rendered into previews
+const (
+	maxTextPreviewLines   = 40
+	maxTextPreviewColumns = 100
+)
+
+// Non-text/* MIME types that are plain text (mostly code)
+var textMimes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/x-sh":       true,
+	"application/x-python":   true,
+	"application/x-yaml":     true,
+	"application/toml":       true,
+	"application/sql":        true,
+}
+
+var pdfPagesRegex = regexp.MustCompile(`(?m)^Pages:\s+([0-9]+)`)
+
+// Whether previews of a file can be rendered from its first page
+func isDocument(mime string) bool {
+	mime, _, _ = strings.Cut(mime, ";")
+	return mime == "application/pdf" || isText(mime)
+}
+
+func isText(mime string) bool {
+	mime, _, _ = strings.Cut(mime, ";")
+	return strings.HasPrefix(mime, "text/") || textMimes[mime]
+}
+
+// Get the number of pages in a PDF
+func getPDFPageCount(fileBytes []byte) (int, error) {
+	tmpPath, err := createTempFile(fileBytes)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpPath)
+
+	infoBytes, err := runCommand(time.Second*30, nil, "pdfinfo", tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	match := pdfPagesRegex.FindSubmatch(infoBytes)
+	if match == nil {
+		return 0, ErrUnsupportedFile
+	}
+	return strconv.Atoi(string(match[1]))
+}
+
+// Render the first page of a PDF or text file into a PNG that fits within maxSize
+func renderDocumentPreview(fileBytes []byte, mime string, maxSize int) ([]byte, error) {
+	// PDFs
+	if strings.HasPrefix(mime, "application/pdf") {
+		tmpPath, err := createTempFile(fileBytes)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmpPath)
+
+		// Writes to stdout when no output file is given
+		return runCommand(
+			time.Second*30,
+			nil,
+			"pdftoppm",
+			"-png",
+			"-singlefile",
+			"-f", "1",
+			"-l", "1",
+			"-scale-to", strconv.Itoa(maxSize),
+			tmpPath,
+		)
+	}
+
+	// Text files
+	if isText(mime) {
+		if !utf8.Valid(fileBytes) {
+			return nil, ErrUnsupportedFile
+		}
+
+		// Only render the start of the file
+		lines := strings.Split(strings.ReplaceAll(string(fileBytes), "\r\n", "\n"), "\n")
+		lines = lines[:min(len(lines), maxTextPreviewLines)]
+		for i, line := range lines {
+			line = strings.ReplaceAll(line, "\t", "    ")
+			if utf8.RuneCountInString(line) > maxTextPreviewColumns {
+				line = string([]rune(line)[:maxTextPreviewColumns])
+			}
+			lines[i] = line
+		}
+		text := []byte(strings.Join(lines, "\n"))
+		if len(bytes.TrimSpace(text)) == 0 {
+			return nil, ErrUnsupportedFile
+		}
+
+		return runCommand(
+			time.Second*30,
+			text,
+			"convert",
+			"-background", "white",
+			"-fill", "black",
+			"-font", "DejaVu-Sans-Mono",
+			"-pointsize", "14",
+			"text:-[0]",
+			"-trim",
+			"+repage",
+			"-bordercolor", "white",
+			"-border", "16",
+			"-resize", strconv.Itoa(maxSize)+"x"+strconv.Itoa(maxSize)+">",
+			"png:-",
+		)
+	}
+
+	return nil, ErrUnsupportedFile
+}
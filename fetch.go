@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidUrl      = errors.New("invalid url")
+	ErrForbiddenTarget = errors.New("forbidden fetch target")
+	ErrFileTooLarge    = errors.New("file too large")
+)
+
+// Maximum number of redirects to follow when fetching a remote file
+const maxFetchRedirects = 3
+
+// Ranges that aren't covered by the net.IP helpers but shouldn't be reachable either
+var forbiddenFetchNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // "this" network
+		"100.64.0.0/10",  // carrier-grade NAT
+		"192.0.0.0/24",   // IETF protocol assignments
+		"198.18.0.0/15",  // benchmarking
+		"240.0.0.0/4",    // reserved
+		"64:ff9b::/96",   // NAT64
+		"64:ff9b:1::/48", // local-use NAT64
+		"2001::/32",      // Teredo
+		"2002::/16",      // 6to4 (can embed private IPv4 addresses)
+		"fec0::/10",      // site-local
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// HTTP client for fetching remote files on behalf of users.
+// Every connection is checked after DNS resolution, so redirects and DNS rebinding
+// can't be used to reach private or loopback addresses.
+var fetchClient = &http.Client{
+	Timeout: time.Second * 30,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: time.Second * 5,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || !isPublicIP(ip) {
+					return ErrForbiddenTarget
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   time.Second * 5,
+		ResponseHeaderTimeout: time.Second * 10,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Second * 30,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxFetchRedirects {
+			return ErrInvalidUrl
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return ErrInvalidUrl
+		}
+		return nil
+	},
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range forbiddenFetchNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetch a remote file, making sure it's no bigger than maxSize.
+// Returns the file's bytes, filename, and MIME type.
+func fetchRemoteFile(rawUrl string, maxSize int64) ([]byte, string, string, error) {
+	// Parse URL
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, "", "", ErrInvalidUrl
+	}
+
+	// Send request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", "", ErrInvalidUrl
+	}
+	req.Header.Set("User-Agent", "Meower-Uploads")
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenTarget) || errors.Is(err, ErrInvalidUrl) {
+			return nil, "", "", ErrForbiddenTarget
+		}
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", errors.New("unexpected status: " + resp.Status)
+	}
+
+	// Read body, without going over the maximum size
+	if resp.ContentLength > maxSize {
+		return nil, "", "", ErrFileTooLarge
+	}
+	fileBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", "", err
+	}
+	if int64(len(fileBytes)) > maxSize {
+		return nil, "", "", ErrFileTooLarge
+	}
+
+	// Get filename and MIME type
+	filename := path.Base(resp.Request.URL.Path)
+	if filename == "/" || filename == "." {
+		filename = ""
+	}
+	mimeType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mimeType = http.DetectContentType(fileBytes)
+	}
+
+	return fileBytes, filename, mimeType, nil
+}
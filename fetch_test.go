@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		// Public
+		{"1.1.1.1", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"2001:4860:4860::8888", true},
+
+		// Loopback
+		{"127.0.0.1", false},
+		{"127.255.0.1", false},
+		{"::1", false},
+
+		// Private
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+
+		// Unspecified
+		{"0.0.0.0", false},
+		{"::", false},
+
+		// Link-local (including cloud metadata services)
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+
+		// Multicast
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"ff01::1", false},
+
+		// Ranges the net.IP helpers don't cover
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b:1::a00:1", false},
+
+		// IPv6 transition ranges
+		{"2002:a00:1::1", false},
+		{"2002:808:808::1", false},
+		{"2001:0:4136:e378:8000:63bf:3fff:fdd2", false},
+		{"fec0::1", false},
+
+		// IPv4-mapped IPv6
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:1.1.1.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("invalid test IP %q", tt.ip)
+			}
+			if got := isPublicIP(ip); got != tt.want {
+				t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
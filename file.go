@@ -8,27 +8,47 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/discord/lilliput"
 	"github.com/getsentry/sentry-go"
 	"github.com/minio/minio-go/v7"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type File struct {
-	Id           string `bson:"_id" json:"id"`
-	Hash         string `bson:"hash" json:"hash"`
-	Bucket       string `bson:"bucket" json:"bucket"`
-	Mime         string `bson:"mime" json:"mime"`
-	Filename     string `bson:"filename,omitempty" json:"filename,omitempty"`
-	Width        int    `bson:"width,omitempty" json:"width,omitempty"`
-	Height       int    `bson:"height,omitempty" json:"height,omitempty"`
-	UploadRegion string `bson:"upload_region" json:"upload_region"`
-	UploadedBy   string `bson:"uploaded_by" json:"uploaded_by"`
-	UploadedAt   int64  `bson:"uploaded_at" json:"uploaded_at"`
-	Claimed      bool   `bson:"claimed,omitempty" json:"claimed"`
+	Id            string           `bson:"_id" json:"id"`
+	Hash          string           `bson:"hash" json:"hash"`
+	ContentHash   string           `bson:"content_hash,omitempty" json:"content_hash,omitempty"`
+	UncroppedHash string           `bson:"uncropped_hash,omitempty" json:"uncropped_hash,omitempty"` // hash of the file before it was cropped, for block checks
+	Bucket        string           `bson:"bucket" json:"bucket"`
+	Mime          string           `bson:"mime" json:"mime"`
+	Filename      string           `bson:"filename,omitempty" json:"filename,omitempty"`
+	Width         int              `bson:"width,omitempty" json:"width,omitempty"`
+	Height        int              `bson:"height,omitempty" json:"height,omitempty"`
+	Size          int64            `bson:"size" json:"size,omitempty"` // always stored, so empty files don't look like they're missing a size
+	Crop          *ImageCrop       `bson:"crop,omitempty" json:"crop,omitempty"`
+	Animated      bool             `bson:"animated,omitempty" json:"animated,omitempty"`
+	BlurHash      string           `bson:"blurhash,omitempty" json:"blurhash,omitempty"`
+	DominantColor string           `bson:"dominant_color,omitempty" json:"dominant_color,omitempty"`
+	Audio         *AudioMetadata   `bson:"audio,omitempty" json:"audio,omitempty"`
+	Transcode     *TranscodeStatus `bson:"transcode,omitempty" json:"transcode,omitempty"`
+	PageCount     int              `bson:"page_count,omitempty" json:"page_count,omitempty"`
+	Converted     bool             `bson:"converted,omitempty" json:"converted,omitempty"` // whether there's a display version in a web format, the original is kept for downloads
+	UploadRegion  string           `bson:"upload_region" json:"upload_region"`
+	UploadedBy    string           `bson:"uploaded_by" json:"uploaded_by"`
+	UploadedAt    int64            `bson:"uploaded_at" json:"uploaded_at"`
+	SourceUrl     string           `bson:"source_url,omitempty" json:"source_url,omitempty"`
+	References    []FileReference  `bson:"references,omitempty" json:"references,omitempty"`
+}
+
+type FileReference struct {
+	Type    string `bson:"type" json:"type"`
+	Id      string `bson:"id" json:"id"`
+	AddedAt int64  `bson:"added_at" json:"added_at"`
 }
 
 func GetFile(id string) (File, error) {
@@ -37,15 +57,29 @@ func GetFile(id string) (File, error) {
 	return f, err
 }
 
-func CreateFile(bucket string, fileBytes []byte, filename string, mime string, uploadedBy string) (File, error) {
+func CreateFile(bucket string, fileBytes []byte, filename string, mime string, uploadedBy string, sourceUrl string, cropParams *CropParams) (File, error) {
 	var f File
 	var err error
 
+	// Check bucket config
+	mime = sniffImageMime(fileBytes, mime)
+	config := buckets[bucket]
+	if config == nil {
+		return f, ErrMismatchedBucket
+	}
+	if !config.AllowsMime(mime) {
+		return f, ErrUnsupportedFile
+	}
+	if !config.AllowCrop {
+		cropParams = nil
+	}
+
 	// Get file hash
 	h := sha256.New()
 	if _, err = h.Write(fileBytes); err != nil {
 		return f, err
 	}
+	contentHashHex := hex.EncodeToString(h.Sum(nil))
 	if _, err = h.Write([]byte(mime)); err != nil {
 		return f, err
 	}
@@ -60,6 +94,58 @@ func CreateFile(bucket string, fileBytes []byte, filename string, mime string, u
 		return f, ErrFileBlocked
 	}
 
+	// Browsers can't display HEIC/HEIF and TIFF images, so they get converted to PNG
+	// (buckets that store images as they were uploaded just keep the original if that fails)
+	imageBytes, imageMime := fileBytes, mime
+	if convertibleImages[mime] != "" {
+		pngBytes, err := convertToPNG(fileBytes, mime)
+		if err == nil {
+			imageBytes, imageMime = pngBytes, "image/png"
+		} else if config.OptimizeSize > 0 {
+			return f, ErrUnsupportedFile
+		} else {
+			sentry.CaptureException(err)
+		}
+	}
+
+	// Get media dimensions
+	var width, height int
+	lilliputDecoder, err := lilliput.NewDecoder(imageBytes)
+	if err == nil {
+		width, height, _ = getMediaDimensions(lilliputDecoder)
+		lilliputDecoder.Close()
+	}
+
+	// Get crop
+	var crop *ImageCrop
+	if cropParams != nil || (config.AutoCrop && len(config.AspectRatio) == 2) {
+		if width == 0 || height == 0 {
+			return f, ErrUnsupportedFile
+		}
+		crop, err = getImageCrop(width, height, cropParams, config.AspectRatio)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	// Crops of the same file need to be stored separately from each other and from the uncropped file
+	uncroppedHashHex := hashHex
+	if crop != nil {
+		if _, err = h.Write([]byte(fmt.Sprintf("crop:%d,%d,%d,%d", crop.X, crop.Y, crop.Width, crop.Height))); err != nil {
+			return f, err
+		}
+		hashHex = hex.EncodeToString(h.Sum(nil))
+
+		// Check block status of the cropped file
+		blocked, err := getBlockStatus(hashHex)
+		if err != nil {
+			return f, err
+		}
+		if blocked {
+			return f, ErrFileBlocked
+		}
+	}
+
 	// Create file ID
 	id, err := generateId()
 	if err != nil {
@@ -70,35 +156,112 @@ func CreateFile(bucket string, fileBytes []byte, filename string, mime string, u
 	f = File{
 		Id:           id,
 		Hash:         hashHex,
+		ContentHash:  contentHashHex,
 		Bucket:       bucket,
 		Mime:         mime,
 		Filename:     cleanFilename(filename),
 		UploadRegion: s3RegionOrder[0],
 		UploadedBy:   uploadedBy,
 		UploadedAt:   time.Now().Unix(),
+		SourceUrl:    sourceUrl,
+		Width:        width,
+		Height:       height,
+		Crop:         crop,
+		Animated:     SupportedImages[imageMime] && isAnimatedImage(imageBytes, imageMime),
+	}
+	if crop != nil {
+		f.Width, f.Height = crop.Width, crop.Height
+		f.UncroppedHash = uncroppedHashHex
 	}
 
-	// Get media dimensions
-	lilliputDecoder, err := lilliput.NewDecoder(fileBytes)
-	if err == nil {
-		f.Width, f.Height, _ = getMediaDimensions(lilliputDecoder)
+	// The original of converted images is only kept if it would've been stored as it was uploaded
+	f.Converted = imageMime != mime && crop == nil && config.OptimizeSize == 0
+
+	// Get placeholder
+	if SupportedImages[imageMime] || strings.HasPrefix(mime, "video/") {
+		f.BlurHash, f.DominantColor, err = getPlaceholder(imageBytes, imageMime, crop)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+
+	// Get audio metadata
+	if strings.HasPrefix(mime, "audio/") {
+		f.Audio, err = getAudioMetadata(fileBytes)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+
+	// Get page count
+	if strings.HasPrefix(mime, "application/pdf") {
+		f.PageCount, err = getPDFPageCount(fileBytes)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
 	}
 
 	// Save file
-	if _, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, f.Bucket, f.Hash, minio.GetObjectOptions{}); err != nil {
-		// Optimization
-		if bucket == "icons" {
-			fileBytes, mime, err = optimizeImage(fileBytes, mime, 256)
+	if objInfo, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, f.Bucket, f.Hash, minio.GetObjectOptions{}); err == nil {
+		f.Size = objInfo.Size
+	} else {
+		// Store the display version of converted images next to the original,
+		// or use it instead of the original if the original isn't kept
+		if f.Converted {
+			displayBytes, displayMime, err := optimizeStaticImage(imageBytes, imageMime, 0, "webp")
 			if err != nil {
+				// Just keep the original if there can't be a display version
+				sentry.CaptureException(err)
+				f.Converted = false
+			} else if _, err = s3Clients[s3RegionOrder[0]].PutObject(
+				ctx,
+				f.Bucket,
+				f.displayKey(),
+				bytes.NewReader(displayBytes),
+				int64(len(displayBytes)),
+				minio.PutObjectOptions{
+					ContentType: displayMime,
+				},
+			); err != nil {
+				log.Println(err)
 				return f, err
 			}
-		} else if bucket == "emojis" {
-			fileBytes, mime, err = optimizeImage(fileBytes, mime, 128)
+		} else {
+			fileBytes, mime = imageBytes, imageMime
+		}
+
+		// Crop
+		if crop != nil {
+			fileBytes, err = cropImage(fileBytes, mime, crop)
+			if err != nil {
+				return f, err
+			}
+		}
+
+		// Static first frame for clients with reduced motion
+		if f.Animated {
+			staticBytes, staticMime, err := optimizeStaticImage(fileBytes, mime, config.OptimizeSize, config.OptimizeFormat)
 			if err != nil {
 				return f, err
 			}
-		} else if bucket == "stickers" {
-			fileBytes, mime, err = optimizeImage(fileBytes, mime, 384)
+			if _, err = s3Clients[s3RegionOrder[0]].PutObject(
+				ctx,
+				f.Bucket,
+				f.staticKey(),
+				bytes.NewReader(staticBytes),
+				int64(len(staticBytes)),
+				minio.PutObjectOptions{
+					ContentType: staticMime,
+				},
+			); err != nil {
+				log.Println(err)
+				return f, err
+			}
+		}
+
+		// Optimization
+		if config.OptimizeSize > 0 {
+			fileBytes, mime, err = optimizeImage(fileBytes, mime, config.OptimizeSize, config.OptimizeFormat)
 			if err != nil {
 				return f, err
 			}
@@ -118,29 +281,202 @@ func CreateFile(bucket string, fileBytes []byte, filename string, mime string, u
 			log.Println(err)
 			return f, err
 		}
+		f.Size = int64(len(fileBytes))
 	}
 
 	// Start loading preview
 	go f.GetPreviewObject()
 
+	// Get transcode status of videos that have already been uploaded, or queue them for transcoding
+	queueForTranscode := false
+	if config.Transcode && strings.HasPrefix(mime, "video/") {
+		f.Transcode, err = getTranscodeStatus(f.Bucket, f.Hash)
+		if err != nil {
+			return f, err
+		}
+		if f.Transcode == nil {
+			f.Transcode = &TranscodeStatus{Status: "pending", UpdatedAt: time.Now().Unix()}
+			queueForTranscode = true
+		}
+	}
+
 	// Create database item
 	if _, err := db.Collection("files").InsertOne(context.TODO(), f); err != nil {
 		return f, err
 	}
 
+	// Queue transcode (after the database item exists, so the worker can update it)
+	if queueForTranscode {
+		if err := queueTranscode(f.Bucket, f.Hash, f.UploadRegion); err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+
 	sentry.CaptureMessage(fmt.Sprintf("Uploaded file %s with hash %s to %s region", f.Id, f.Hash, f.UploadRegion))
 
 	return f, nil
 }
 
+// Create a file from one that has already been uploaded to the bucket with the same
+// content hash (plain SHA-256 of the file) and MIME type, without transferring it again.
+// Files in private buckets can only be created from files the same user uploaded.
+// Returns mongo.ErrNoDocuments if there's no existing file to create it from.
+func CreateFileFromHash(bucket string, contentHash string, mime string, filename string, uploadedBy string) (File, error) {
+	var f File
+	mime = normaliseImageMime(mime)
+
+	// Get existing file
+	config := buckets[bucket]
+	if config == nil {
+		return f, ErrMismatchedBucket
+	}
+	filter := bson.M{
+		"bucket":       bucket,
+		"content_hash": contentHash,
+		"mime":         mime,
+		"crop.custom":  bson.M{"$ne": true},
+	}
+	if config.Private {
+		filter["uploaded_by"] = uploadedBy
+	}
+	if err := db.Collection("files").FindOne(context.TODO(), filter).Decode(&f); err != nil {
+		return f, err
+	}
+
+	// Check block status of the file and the file it was cropped from
+	for _, hashHex := range []string{f.Hash, f.UncroppedHash} {
+		if hashHex == "" {
+			continue
+		}
+		blocked, err := getBlockStatus(hashHex)
+		if err != nil {
+			return f, err
+		}
+		if blocked {
+			return f, ErrFileBlocked
+		}
+	}
+
+	// Make sure the object still exists
+	obj, _, err := f.GetObject()
+	if err != nil {
+		return f, mongo.ErrNoDocuments
+	}
+	obj.Close()
+
+	// Create file ID
+	id, err := generateId()
+	if err != nil {
+		return f, err
+	}
+
+	// Create file details, keeping the metadata of the existing file
+	f.Id = id
+	f.Filename = cleanFilename(filename)
+	f.UploadedBy = uploadedBy
+	f.UploadedAt = time.Now().Unix()
+	f.SourceUrl = ""
+	f.References = nil
+
+	// Create database item
+	if _, err := db.Collection("files").InsertOne(context.TODO(), f); err != nil {
+		return f, err
+	}
+
+	sentry.CaptureMessage(fmt.Sprintf("Created file %s from existing hash %s", f.Id, f.Hash))
+
+	return f, nil
+}
+
 func (f *File) GetObject() (*minio.Object, *minio.ObjectInfo, error) {
+	return f.getObject(f.Hash, f.UploadRegion)
+}
+
+// Get the static first frame of an animated file.
+// It's generated from the file's object if it doesn't exist yet
+// (e.g. for the same file uploaded before static frames were stored).
+func (f *File) GetStaticObject() (*minio.Object, *minio.ObjectInfo, error) {
+	obj, objInfo, err := f.getObject(f.staticKey(), f.UploadRegion)
+	if err == nil {
+		return obj, objInfo, nil
+	}
+	return f.generateObject(f.staticKey(), func(imageBytes []byte, mime string) ([]byte, string, error) {
+		config := buckets[f.Bucket]
+		if config == nil {
+			return nil, "", ErrMismatchedBucket
+		}
+		return optimizeStaticImage(imageBytes, mime, config.OptimizeSize, config.OptimizeFormat)
+	})
+}
+
+// Generate an object from the file's object, and put it in the local region at key
+func (f *File) generateObject(key string, generate func(fileBytes []byte, mime string) ([]byte, string, error)) (*minio.Object, *minio.ObjectInfo, error) {
+	// Get file's object
+	obj, objInfo, err := f.GetObject()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer obj.Close()
+	fileBytes, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Generate object
+	newBytes, newMime, err := generate(fileBytes, objInfo.ContentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = s3Clients[s3RegionOrder[0]].PutObject(
+		ctx,
+		f.Bucket,
+		key,
+		bytes.NewReader(newBytes),
+		int64(len(newBytes)),
+		minio.PutObjectOptions{
+			ContentType: newMime,
+		},
+	); err != nil {
+		return nil, nil, err
+	}
+
+	return f.getObject(key, s3RegionOrder[0])
+}
+
+// Get the version of a converted image that browsers can display
+func (f *File) GetDisplayObject() (*minio.Object, *minio.ObjectInfo, error) {
+	return f.getObject(f.displayKey(), f.UploadRegion)
+}
+
+// Get the web-safe MP4 rendition of a video
+func (f *File) GetTranscodedObject() (*minio.Object, *minio.ObjectInfo, error) {
+	if f.Transcode == nil || f.Transcode.Status != "completed" {
+		return nil, nil, mongo.ErrNoDocuments
+	}
+	return f.getObject(f.transcodedKey(), f.Transcode.Region)
+}
+
+func (f *File) staticKey() string {
+	return f.Hash + ".static"
+}
+
+func (f *File) displayKey() string {
+	return f.Hash + ".display"
+}
+
+func (f *File) transcodedKey() string {
+	return f.Hash + ".mp4"
+}
+
+// Get an object from the local region, or otherwise from the region it was put in
+func (f *File) getObject(key string, region string) (*minio.Object, *minio.ObjectInfo, error) {
 	var objInfo minio.ObjectInfo
 	var err error
 
 	// Attempt getting object locally
-	objInfo, err = s3Clients[s3RegionOrder[0]].StatObject(ctx, f.Bucket, f.Hash, minio.StatObjectOptions{})
+	objInfo, err = s3Clients[s3RegionOrder[0]].StatObject(ctx, f.Bucket, key, minio.StatObjectOptions{})
 	if err == nil {
-		obj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, f.Bucket, f.Hash, minio.GetObjectOptions{})
+		obj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, f.Bucket, key, minio.GetObjectOptions{})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -148,10 +484,10 @@ func (f *File) GetObject() (*minio.Object, *minio.ObjectInfo, error) {
 		return obj, &objInfo, nil
 	}
 
-	// Otherwise, go to the upload region
-	objInfo, err = s3Clients[f.UploadRegion].StatObject(ctx, f.Bucket, f.Hash, minio.StatObjectOptions{})
+	// Otherwise, go to the region it was put in
+	objInfo, err = s3Clients[region].StatObject(ctx, f.Bucket, key, minio.StatObjectOptions{})
 	if err == nil {
-		obj, err := s3Clients[f.UploadRegion].GetObject(ctx, f.Bucket, f.Hash, minio.GetObjectOptions{})
+		obj, err := s3Clients[region].GetObject(ctx, f.Bucket, key, minio.GetObjectOptions{})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -164,32 +500,52 @@ func (f *File) GetObject() (*minio.Object, *minio.ObjectInfo, error) {
 
 func (f *File) GetPreviewObject() (*minio.Object, *minio.ObjectInfo, error) {
 	// Get cached preview
-	previewObjInfo, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, "attachment-previews", f.Hash, minio.StatObjectOptions{})
+	previewObjInfo, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, previewsBucket, f.Hash, minio.StatObjectOptions{})
 	if err == nil {
-		previewObj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, "attachment-previews", f.Hash, minio.GetObjectOptions{})
+		previewObj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, previewsBucket, f.Hash, minio.GetObjectOptions{})
 		return previewObj, &previewObjInfo, err
 	} else {
 		err = nil
 	}
 
-	// Get full object
-	obj, objInfo, err := f.GetObject()
+	// Get full object (or the display version of converted images)
+	getObject := f.GetObject
+	if f.Converted {
+		getObject = f.GetDisplayObject
+	}
+	obj, objInfo, err := getObject()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Make sure the file is compatible
-	if f.Bucket != "attachments" || !SupportedImages[objInfo.ContentType] || objInfo.Size > 10<<20 {
+	config := buckets[f.Bucket]
+	if config == nil || config.PreviewSize == 0 {
+		return obj, objInfo, nil // silent fail
+	}
+	document := isDocument(objInfo.ContentType)
+	if !(SupportedImages[objInfo.ContentType] && objInfo.Size <= 10<<20) && !(document && objInfo.Size <= maxDocumentPreviewSize) {
 		return obj, objInfo, nil // silent fail
 	}
 
-	// Optimize image
+	// Get image
 	imgBytes, err := io.ReadAll(obj)
 	if err != nil {
 		sentry.CaptureException(err)
 		return obj, objInfo, nil // silent fail
 	}
-	optimizedImgBytes, newMime, err := optimizeImage(imgBytes, objInfo.ContentType, 720)
+	imgMime := objInfo.ContentType
+	if document {
+		imgBytes, err = renderDocumentPreview(imgBytes, objInfo.ContentType, config.PreviewSize)
+		if err != nil {
+			sentry.CaptureException(err)
+			return obj, objInfo, nil // silent fail
+		}
+		imgMime = "image/png"
+	}
+
+	// Optimize image
+	optimizedImgBytes, newMime, err := optimizeImage(imgBytes, imgMime, config.PreviewSize, "webp")
 	if err != nil {
 		sentry.CaptureException(err)
 		return obj, objInfo, nil // silent fail
@@ -197,13 +553,13 @@ func (f *File) GetPreviewObject() (*minio.Object, *minio.ObjectInfo, error) {
 
 	// Make sure that the optimized image is actually better (sometimes it's not)
 	if len(optimizedImgBytes) > len(imgBytes) {
-		optimizedImgBytes = imgBytes
+		optimizedImgBytes, newMime = imgBytes, imgMime
 	}
 
 	// Cache preview
 	_, err = s3Clients[s3RegionOrder[0]].PutObject(
 		ctx,
-		"attachment-previews",
+		previewsBucket,
 		f.Hash,
 		bytes.NewReader(optimizedImgBytes),
 		int64(len(optimizedImgBytes)),
@@ -219,18 +575,110 @@ func (f *File) GetPreviewObject() (*minio.Object, *minio.ObjectInfo, error) {
 	return f.GetPreviewObject()
 }
 
-func (f *File) Claim() error {
-	if f.Claimed {
-		return ErrFileAlreadyClaimed
+// Atomically add the first reference to the file.
+// Fails if anything else is already referencing the file.
+func (f *File) Claim(refType string, refId string) error {
+	return f.addReference(refType, refId, true)
+}
+
+// Whether previews may have been cached for the file.
+// Files in buckets that have been removed from the registry are assumed to have previews.
+func (f *File) hasPreviews() bool {
+	config := buckets[f.Bucket]
+	return config == nil || config.PreviewSize > 0
+}
+
+// Add a reference to the file, does nothing if the reference already exists.
+func (f *File) AddReference(refType string, refId string) error {
+	return f.addReference(refType, refId, false)
+}
+
+func (f *File) addReference(refType string, refId string, exclusive bool) error {
+	if !ReferenceTypes[refType] || refId == "" {
+		return ErrInvalidReference
 	}
-	_, err := db.Collection("files").UpdateOne(context.TODO(), bson.M{"_id": f.Id}, bson.M{"$set": bson.M{"claimed": true}})
-	return err
+
+	ref := FileReference{
+		Type:    refType,
+		Id:      refId,
+		AddedAt: time.Now().Unix(),
+	}
+	filter := bson.M{"_id": f.Id}
+	if exclusive {
+		filter["references.0"] = bson.M{"$exists": false}
+	} else {
+		filter["references"] = bson.M{"$not": bson.M{"$elemMatch": bson.M{"type": ref.Type, "id": ref.Id}}}
+	}
+	res, err := db.Collection("files").UpdateOne(context.TODO(), filter, bson.M{"$push": bson.M{"references": ref}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// Work out whether the file was referenced or deleted before us
+		opts := options.Count()
+		opts.SetLimit(1)
+		count, err := db.Collection("files").CountDocuments(context.TODO(), bson.M{"_id": f.Id}, opts)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return mongo.ErrNoDocuments
+		}
+		if exclusive {
+			return ErrFileAlreadyClaimed
+		}
+		return nil
+	}
+
+	f.References = append(f.References, ref)
+
+	return nil
+}
+
+// Remove a reference from the file.
+// The file gets deleted by the cleanup thread once nothing references it.
+func (f *File) RemoveReference(refType string, refId string) error {
+	res, err := db.Collection("files").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": f.Id},
+		bson.M{"$pull": bson.M{"references": bson.M{"type": refType, "id": refId}}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	for i, ref := range f.References {
+		if ref.Type == refType && ref.Id == refId {
+			f.References = append(f.References[:i], f.References[i+1:]...)
+			break
+		}
+	}
+
+	return nil
 }
 
 func (f *File) Delete() error {
+	_, err := f.deleteWhere(bson.M{"_id": f.Id})
+	return err
+}
+
+// Delete the file only if nothing is referencing it.
+// Returns whether the file was deleted.
+func (f *File) DeleteIfUnreferenced() (bool, error) {
+	return f.deleteWhere(bson.M{"_id": f.Id, "references.0": bson.M{"$exists": false}})
+}
+
+func (f *File) deleteWhere(filter bson.M) (bool, error) {
 	// Delete database row
-	if _, err := db.Collection("files").DeleteOne(context.TODO(), bson.M{"_id": f.Id}); err != nil {
-		return err
+	res, err := db.Collection("files").DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return false, err
+	}
+	if res.DeletedCount == 0 {
+		return false, nil
 	}
 
 	// Clean-up object if nothing else is referencing it
@@ -238,16 +686,110 @@ func (f *File) Delete() error {
 	opts.SetLimit(1)
 	referencedCount, err := db.Collection("files").CountDocuments(context.TODO(), bson.M{"hash": f.Hash}, opts)
 	if err != nil {
-		return err
+		return true, err
 	}
 	if referencedCount == 0 {
 		for _, s3Client := range s3Clients {
-			go s3Client.RemoveObject(ctx, f.Bucket, f.Hash, minio.RemoveObjectOptions{})
-			if f.Bucket == "attachments" {
-				go s3Client.RemoveObject(ctx, "attachment-previews", f.Hash, minio.RemoveObjectOptions{})
-			}
+			go f.removeObjects(s3Client)
 		}
 	}
 
+	return true, nil
+}
+
+// Remove the object of a file from a region, along with its static frame,
+// preview, and every cached format variant (which all start with the hash).
+func (f *File) removeObjects(s3Client *minio.Client) error {
+	bucketNames := []string{f.Bucket}
+	if f.hasPreviews() {
+		bucketNames = append(bucketNames, previewsBucket)
+	}
+	for _, bucketName := range bucketNames {
+		for objInfo := range s3Client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: f.Hash, Recursive: true}) {
+			if objInfo.Err != nil {
+				return objInfo.Err
+			}
+			if err := s3Client.RemoveObject(ctx, bucketName, objInfo.Key, minio.RemoveObjectOptions{}); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+type PurgeReport struct {
+	FileIds        []string
+	RemovedHashes  []string
+	RetainedHashes []string
+	DataExportIds  []string
+}
+
+// Delete every file uploaded by a user, along with their objects in every region
+// unless another user has uploaded a file with the same hash, and their data exports.
+// Objects are removed before database rows, so a failed purge can just be run again.
+func PurgeUserFiles(userId string) (PurgeReport, error) {
+	var report PurgeReport
+
+	// Get files
+	cur, err := db.Collection("files").Find(context.TODO(), bson.M{"uploaded_by": userId})
+	if err != nil {
+		return report, err
+	}
+	var files []File
+	if err := cur.All(context.TODO(), &files); err != nil {
+		return report, err
+	}
+
+	// Remove objects that nothing else is referencing
+	// (objects are per bucket, so the same hash in another bucket doesn't count)
+	checkedObjects := make(map[string]bool)
+	for _, f := range files {
+		if checkedObjects[f.Bucket+"/"+f.Hash] {
+			continue
+		}
+		checkedObjects[f.Bucket+"/"+f.Hash] = true
+
+		opts := options.Count()
+		opts.SetLimit(1)
+		referencedCount, err := db.Collection("files").CountDocuments(context.TODO(), bson.M{
+			"bucket":      f.Bucket,
+			"hash":        f.Hash,
+			"uploaded_by": bson.M{"$ne": userId},
+		}, opts)
+		if err != nil {
+			return report, err
+		}
+		if referencedCount > 0 {
+			report.RetainedHashes = append(report.RetainedHashes, f.Hash)
+			continue
+		}
+
+		for _, s3Client := range s3Clients {
+			if err := f.removeObjects(s3Client); err != nil {
+				return report, err
+			}
+		}
+		report.RemovedHashes = append(report.RemovedHashes, f.Hash)
+	}
+
+	// Delete database rows
+	for _, f := range files {
+		if _, err := db.Collection("files").DeleteOne(context.TODO(), bson.M{"_id": f.Id}); err != nil {
+			return report, err
+		}
+		report.FileIds = append(report.FileIds, f.Id)
+	}
+
+	// Delete data exports
+	report.DataExportIds, err = purgeUserDataExports(userId)
+	if err != nil {
+		return report, err
+	}
+
+	// Purge from CF cache
+	go purgeFilesFromCDN(files)
+
+	sentry.CaptureMessage(fmt.Sprintf("Purged %d files and %d data exports of %s", len(report.FileIds), len(report.DataExportIds), userId))
+
+	return report, nil
+}
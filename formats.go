@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/minio/minio-go/v7"
+)
+
+// Formats generated images can be served in
+var imageFormatMimes = map[string]string{
+	"avif": "image/avif",
+	"webp": "image/webp",
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// Pick the best format to serve a generated image in from a request's Accept header.
+// Picks whichever of AVIF, WebP, and the fallback format has the highest q-value
+// (preferring them in that order), where the fallback is GIF for animated images,
+// JPEG for images that were uploaded as JPEGs, and PNG for everything else.
+// AVIF and WebP have to be listed explicitly, since browsers that can't display them
+// still send image/* and */*. Animated images are never served as AVIF.
+func negotiateImageFormat(accept string, animated bool, originalMime string) string {
+	// Get the q-value of each accepted MIME type
+	qValues := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		if mime == "" {
+			continue
+		}
+		qValues[mime] = 1
+		for _, param := range params[1:] {
+			q, found := strings.CutPrefix(strings.TrimSpace(param), "q=")
+			if qValue, err := strconv.ParseFloat(q, 64); found && err == nil {
+				qValues[mime] = qValue
+			}
+		}
+	}
+
+	// Get fallback format
+	fallback := "png"
+	if animated {
+		fallback = "gif"
+	} else if originalMime == "image/jpeg" {
+		fallback = "jpeg"
+	}
+	fallbackQValue, found := qValues[imageFormatMimes[fallback]]
+	if !found {
+		fallbackQValue, found = qValues["image/*"]
+	}
+	if !found {
+		fallbackQValue, found = qValues["*/*"]
+	}
+	if !found && len(qValues) == 0 {
+		fallbackQValue = 1
+	}
+
+	// Pick format
+	format, bestQValue := fallback, fallbackQValue
+	if qValue := qValues["image/webp"]; qValue > 0 && qValue >= bestQValue {
+		format, bestQValue = "webp", qValue
+	}
+	if qValue := qValues["image/avif"]; qValue > 0 && qValue >= bestQValue && !animated {
+		format = "avif"
+	}
+	return format
+}
+
+// Get an image object in another format, converting it from the source object and
+// caching it in the local region next to the source (at key + "." + format) if needed.
+// Variants are only ever cached in the local region, so each region converts an image
+// the first time it's requested there rather than fetching the variant from another region.
+// The source object is returned as it is if it's already in the right format or isn't an image.
+func (f *File) getObjectAs(
+	format string,
+	bucket string,
+	key string,
+	getSource func() (*minio.Object, *minio.ObjectInfo, error),
+) (*minio.Object, *minio.ObjectInfo, error) {
+	variantKey := key + "." + format
+
+	// Get cached variant
+	variantObjInfo, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, bucket, variantKey, minio.StatObjectOptions{})
+	if err == nil {
+		variantObj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, bucket, variantKey, minio.GetObjectOptions{})
+		return variantObj, &variantObjInfo, err
+	}
+
+	// Get source object
+	obj, objInfo, err := getSource()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Make sure the source needs converting
+	if objInfo.ContentType == imageFormatMimes[format] || !SupportedImages[objInfo.ContentType] || objInfo.Size > 10<<20 {
+		return obj, objInfo, nil
+	}
+
+	// Convert image
+	imgBytes, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	convertedImgBytes, err := convertImage(imgBytes, objInfo.ContentType, format)
+	if err != nil {
+		sentry.CaptureException(err)
+		return obj, objInfo, nil // silent fail
+	}
+
+	// Cache variant
+	if _, err = s3Clients[s3RegionOrder[0]].PutObject(
+		ctx,
+		bucket,
+		variantKey,
+		bytes.NewReader(convertedImgBytes),
+		int64(len(convertedImgBytes)),
+		minio.PutObjectOptions{
+			ContentType: imageFormatMimes[format],
+		},
+	); err != nil {
+		return nil, nil, err
+	}
+
+	// Recursion! (should now pull from cache)
+	return f.getObjectAs(format, bucket, key, getSource)
+}
+
+// Convert an image to another format without resizing it
+func convertImage(imageBytes []byte, mime string, format string) ([]byte, error) {
+	inputFormat := imageMagickFormats[mime]
+	if inputFormat == "" || imageFormatMimes[format] == "" {
+		return nil, ErrUnsupportedFile
+	}
+
+	// Only GIFs and WebPs keep every frame
+	var args []string
+	switch format {
+	case "gif":
+		args = []string{inputFormat + ":-", "-coalesce", "-layers", "Optimize"}
+	case "webp":
+		args = []string{inputFormat + ":-", "-quality", "85"}
+	case "avif":
+		args = []string{inputFormat + ":-[0]", "-quality", "60"}
+	case "jpeg":
+		args = []string{inputFormat + ":-[0]", "-quality", "85"}
+	default:
+		args = []string{inputFormat + ":-[0]"}
+	}
+	args = append(args, format+":-")
+
+	return runCommand(time.Second*60, imageBytes, "convert", args...)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestNegotiateImageFormat(t *testing.T) {
+	const chrome = "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+	const oldSafari = "image/png,image/svg+xml,image/*;q=0.8,video/*;q=0.8,*/*;q=0.5"
+
+	tests := []struct {
+		name         string
+		accept       string
+		animated     bool
+		originalMime string
+		want         string
+	}{
+		{"no accept header", "", false, "image/png", "png"},
+		{"no accept header for a JPEG", "", false, "image/jpeg", "jpeg"},
+		{"no accept header for an animated image", "", true, "image/gif", "gif"},
+		{"modern browser", chrome, false, "image/png", "avif"},
+		{"modern browser with an animated image", chrome, true, "image/gif", "webp"},
+		{"old browser", oldSafari, false, "image/png", "png"},
+		{"old browser with a JPEG", oldSafari, false, "image/jpeg", "jpeg"},
+		{"wildcards don't imply AVIF or WebP", "image/*,*/*", false, "image/png", "png"},
+		{"WebP only", "image/webp", false, "image/png", "webp"},
+		{"AVIF refused", "image/avif;q=0,image/webp", false, "image/png", "webp"},
+		{"everything modern refused", "image/avif;q=0,image/webp;q=0,*/*", false, "image/png", "png"},
+		{"fallback preferred by q-value", "image/webp;q=0.5,image/png", false, "image/png", "png"},
+		{"fallback preferred by image wildcard q-value", "image/webp;q=0.5,image/*", false, "image/png", "png"},
+		{"fallback preferred by any wildcard q-value", "image/webp;q=0.5,*/*;q=0.9", false, "image/png", "png"},
+		{"WebP preferred over a wildcard", "image/webp,*/*;q=0.8", false, "image/jpeg", "webp"},
+		{"WebP preferred over AVIF", "image/avif;q=0.5,image/webp;q=0.9", false, "image/png", "webp"},
+		{"image wildcard refused", "image/webp;q=0.1,image/*;q=0", false, "image/png", "webp"},
+		{"case and whitespace", " Image/WebP ; q=1 ", false, "image/png", "webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateImageFormat(tt.accept, tt.animated, tt.originalMime); got != tt.want {
+				t.Errorf("negotiateImageFormat(%q, %v, %q) = %q, want %q", tt.accept, tt.animated, tt.originalMime, got, tt.want)
+			}
+		})
+	}
+}
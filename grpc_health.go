@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/meower-media-co/Meower-Uploads/grpc_uploads"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Keep the gRPC health server's statuses up to date with the state of
+// MongoDB, Redis, and every MinIO region.
+//
+// The overall ("") and uploads.Uploads statuses only depend on MongoDB, Redis,
+// and the local MinIO region. Every region also gets its own "minio/<region>" status.
+func runGrpcHealthChecks(hs *health.Server) {
+	for {
+		serving := true
+
+		// MongoDB
+		if err := checkHealth(func(ctx context.Context) error {
+			return db.Client().Ping(ctx, nil)
+		}); err != nil {
+			serving = false
+		}
+
+		// Redis
+		if err := checkHealth(func(ctx context.Context) error {
+			return rdb.Ping(ctx).Err()
+		}); err != nil {
+			serving = false
+		}
+
+		// MinIO regions
+		for _, region := range s3RegionOrder {
+			err := checkHealth(func(ctx context.Context) error {
+				_, err := s3Clients[region].ListBuckets(ctx)
+				return err
+			})
+			if err != nil {
+				hs.SetServingStatus("minio/"+region, healthpb.HealthCheckResponse_NOT_SERVING)
+				if region == s3RegionOrder[0] {
+					serving = false
+				}
+			} else {
+				hs.SetServingStatus("minio/"+region, healthpb.HealthCheckResponse_SERVING)
+			}
+		}
+
+		// Overall status
+		status := healthpb.HealthCheckResponse_SERVING
+		if !serving {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		hs.SetServingStatus("", status)
+		hs.SetServingStatus(pb.Uploads_ServiceDesc.ServiceName, status)
+
+		time.Sleep(time.Second * 10)
+	}
+}
+
+func checkHealth(check func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return check(ctx)
+}
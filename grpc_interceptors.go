@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"os"
+	"strings"
+
+	pb "github.com/meower-media-co/Meower-Uploads/grpc_uploads"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Domain used in error details sent to gRPC clients
+const grpcErrorDomain = "uploads.meower.org"
+
+// Permission required to call each gRPC method, anything not listed here or in
+// grpcServicePermissions is only allowed for clients with every permission (*)
+var grpcMethodPermissions = map[string]string{
+	pb.Uploads_GetStorageUsage_FullMethodName:  "read",
+	pb.Uploads_ClaimFile_FullMethodName:        "write",
+	pb.Uploads_AddReference_FullMethodName:     "write",
+	pb.Uploads_RemoveReference_FullMethodName:  "write",
+	pb.Uploads_DeleteFile_FullMethodName:       "delete",
+	pb.Uploads_ClearFiles_FullMethodName:       "delete",
+	pb.Uploads_CreateDataExport_FullMethodName: "write",
+	pb.Uploads_PurgeUserFiles_FullMethodName:   "delete",
+}
+
+// Permission required to call every method of each debugging gRPC service
+var grpcServicePermissions = map[string]string{
+	"grpc.channelz.v1.Channelz":                "debug",
+	"grpc.reflection.v1.ServerReflection":      "debug",
+	"grpc.reflection.v1alpha.ServerReflection": "debug",
+}
+
+func grpcUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	// Load balancers need to be able to check health without a token
+	if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+		return handler(ctx, req)
+	}
+
+	if err := checkGrpcToken(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkGrpcPermission(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, grpcStatusFromError(err)
+	}
+	return resp, nil
+}
+
+func grpcStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// Load balancers need to be able to watch health without a token
+	if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+		return handler(srv, ss)
+	}
+
+	if err := checkGrpcToken(ss.Context()); err != nil {
+		return err
+	}
+	if err := checkGrpcPermission(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	if err := handler(srv, ss); err != nil {
+		return grpcStatusFromError(err)
+	}
+	return nil
+}
+
+// Make sure the request has the correct x-token metadata
+func checkGrpcToken(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("x-token")) == 0 {
+		return grpcStatusFromError(ErrUnauthorized)
+	}
+	if subtle.ConstantTimeCompare([]byte(md.Get("x-token")[0]), []byte(os.Getenv("GRPC_UPLOADS_TOKEN"))) != 1 {
+		return grpcStatusFromError(ErrUnauthorized)
+	}
+	return nil
+}
+
+// Make sure the client certificate's identity is allowed to call the method
+func checkGrpcPermission(ctx context.Context, fullMethod string) error {
+	if grpcClientPermissions == nil {
+		return nil
+	}
+
+	// Get client identity
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return grpcStatusFromError(ErrForbidden)
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return grpcStatusFromError(ErrForbidden)
+	}
+	identity := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+
+	// Check permissions
+	required := grpcMethodPermissions[fullMethod]
+	if required == "" {
+		service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+		required = grpcServicePermissions[service]
+	}
+	for _, permission := range grpcClientPermissions[identity] {
+		if permission == "*" || (required != "" && permission == required) {
+			return nil
+		}
+	}
+	return grpcStatusFromError(ErrForbidden)
+}
+
+// Convert a domain error into a gRPC status error with details clients can act on
+func grpcStatusFromError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var code codes.Code
+	var reason string
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		code, reason = codes.NotFound, "FILE_NOT_FOUND"
+	case errors.Is(err, ErrUnauthorized):
+		code, reason = codes.Unauthenticated, "INVALID_TOKEN"
+	case errors.Is(err, ErrForbidden):
+		code, reason = codes.PermissionDenied, "CLIENT_NOT_PERMITTED"
+	case errors.Is(err, ErrFileBlocked):
+		code, reason = codes.PermissionDenied, "FILE_BLOCKED"
+	case errors.Is(err, ErrFileAlreadyClaimed):
+		code, reason = codes.AlreadyExists, "FILE_ALREADY_CLAIMED"
+	case errors.Is(err, ErrMismatchedBucket):
+		code, reason = codes.FailedPrecondition, "MISMATCHED_BUCKET"
+	case errors.Is(err, ErrInvalidReference):
+		code, reason = codes.InvalidArgument, "INVALID_REFERENCE"
+	case errors.Is(err, ErrUnsupportedFile):
+		code, reason = codes.InvalidArgument, "UNSUPPORTED_FILE"
+	default:
+		// Don't leak internal errors to clients (handlers report them to Sentry)
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	st, detailsErr := status.New(code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: grpcErrorDomain,
+	})
+	if detailsErr != nil {
+		return status.Error(code, err.Error())
+	}
+	return st.Err()
+}
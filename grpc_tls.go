@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Permissions for each client certificate common name, nil means every client has every permission
+var grpcClientPermissions map[string][]string
+
+// Keeps the gRPC server's certificate and client CA up to date with the files on disk,
+// so certificates can be rotated without restarting the service
+type grpcCertReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    [3]time.Time
+	lastChecked time.Time
+}
+
+// Get a TLS config for the gRPC server based on GRPC_UPLOADS_TLS_* env vars.
+// Returns nil if TLS isn't configured.
+func loadGrpcTLSConfig() (*tls.Config, error) {
+	r := &grpcCertReloader{
+		certFile:     os.Getenv("GRPC_UPLOADS_TLS_CERT"),
+		keyFile:      os.Getenv("GRPC_UPLOADS_TLS_KEY"),
+		clientCAFile: os.Getenv("GRPC_UPLOADS_TLS_CLIENT_CA"),
+	}
+	if r.certFile == "" && r.keyFile == "" {
+		return nil, nil
+	}
+	if r.certFile == "" || r.keyFile == "" {
+		return nil, errors.New("both GRPC_UPLOADS_TLS_CERT and GRPC_UPLOADS_TLS_KEY must be set")
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs, err := r.get()
+			if err != nil {
+				return nil, err
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+			if clientCAs != nil {
+				cfg.ClientCAs = clientCAs
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}, nil
+}
+
+// Get the current certificate and client CA pool, reloading them if the files have changed
+func (r *grpcCertReloader) get() (*tls.Certificate, *x509.CertPool, error) {
+	r.mu.RLock()
+	stale := time.Since(r.lastChecked) > 10*time.Second
+	r.mu.RUnlock()
+	if stale {
+		if err := r.reload(); err != nil {
+			// Keep using the old certificate if the new one is broken
+			sentry.CaptureException(err)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.clientCAs, nil
+}
+
+func (r *grpcCertReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastChecked = time.Now()
+
+	// Check whether anything has changed
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if r.cert != nil && modTimes == r.modTimes {
+		return nil
+	}
+
+	// Load certificate
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	// Load client CA
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		caCert, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caCert) {
+			return errors.New("failed to parse GRPC_UPLOADS_TLS_CLIENT_CA")
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// Get the permissions for each client certificate common name from GRPC_UPLOADS_CLIENT_PERMISSIONS.
+// Returns nil if every client should have every permission.
+func loadGrpcClientPermissions() (map[string][]string, error) {
+	if os.Getenv("GRPC_UPLOADS_CLIENT_PERMISSIONS") == "" {
+		return nil, nil
+	}
+	var clientPermissions map[string][]string
+	err := json.Unmarshal([]byte(os.Getenv("GRPC_UPLOADS_CLIENT_PERMISSIONS")), &clientPermissions)
+	return clientPermissions, err
+}
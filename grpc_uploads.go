@@ -1,18 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
 
 	"github.com/getsentry/sentry-go"
 	pb "github.com/meower-media-co/Meower-Uploads/grpc_uploads"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -21,12 +15,6 @@ type grpcUploadsServer struct {
 }
 
 func (s grpcUploadsServer) ClaimFile(ctx context.Context, req *pb.ClaimFileReq) (*pb.ClaimFileResp, error) {
-	// Check token
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("x-token")) == 0 || md.Get("x-token")[0] != os.Getenv("GRPC_UPLOADS_TOKEN") {
-		return nil, ErrUnauthorized
-	}
-
 	// Get file
 	f, err := GetFile(req.Id)
 	if err != nil {
@@ -42,39 +30,66 @@ func (s grpcUploadsServer) ClaimFile(ctx context.Context, req *pb.ClaimFileReq)
 	}
 
 	// Claim file
-	err = f.Claim()
+	err = f.Claim(req.GetReference().GetType(), req.GetReference().GetId())
 	if err != nil {
-		if err != ErrFileAlreadyClaimed {
+		if err != ErrFileAlreadyClaimed && err != ErrInvalidReference && err != mongo.ErrNoDocuments {
 			sentry.CaptureException(err)
 		}
 		return nil, err
 	}
 
 	// Get object info
-	_, objInfo, err := f.GetObject()
+	obj, objInfo, err := f.GetObject()
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil, err
 	}
+	obj.Close()
+
+	// Clients get the MIME type of what gets displayed, which is the display version of
+	// converted images whose original was kept (the stored object already is the display version otherwise)
+	mime := objInfo.ContentType
+	if f.Converted {
+		displayObj, displayObjInfo, err := f.GetDisplayObject()
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		displayObj.Close()
+		mime = displayObjInfo.ContentType
+	}
+
+	// Get audio metadata
+	var audio *pb.AudioMetadata
+	if f.Audio != nil {
+		audio = &pb.AudioMetadata{
+			Duration:   f.Audio.Duration,
+			Codec:      f.Audio.Codec,
+			Bitrate:    f.Audio.Bitrate,
+			SampleRate: int32(f.Audio.SampleRate),
+			Channels:   int32(f.Audio.Channels),
+			Waveform:   make([]int32, len(f.Audio.Waveform)),
+		}
+		for i, point := range f.Audio.Waveform {
+			audio.Waveform[i] = int32(point)
+		}
+	}
 
 	// Return file details
 	return &pb.ClaimFileResp{
-		Id:       f.Id,
-		Mime:     objInfo.ContentType,
-		Filename: f.Filename,
-		Size:     objInfo.Size,
-		Width:    int32(f.Width),
-		Height:   int32(f.Height),
+		Id:            f.Id,
+		Mime:          mime,
+		Filename:      f.Filename,
+		Size:          objInfo.Size,
+		Width:         int32(f.Width),
+		Height:        int32(f.Height),
+		Blurhash:      f.BlurHash,
+		DominantColor: f.DominantColor,
+		Audio:         audio,
 	}, nil
 }
 
 func (s grpcUploadsServer) DeleteFile(ctx context.Context, req *pb.DeleteFileReq) (*emptypb.Empty, error) {
-	// Check token
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("x-token")) == 0 || md.Get("x-token")[0] != os.Getenv("GRPC_UPLOADS_TOKEN") {
-		return nil, ErrUnauthorized
-	}
-
 	// Get file
 	f, err := GetFile(req.Id)
 	if err != nil {
@@ -91,69 +106,135 @@ func (s grpcUploadsServer) DeleteFile(ctx context.Context, req *pb.DeleteFileReq
 	}
 
 	// Purge from CF cache
-	go func() {
-		// Get token, zone ID, and URL
-		token := os.Getenv("CF_TOKEN")
-		zoneId := os.Getenv("CF_ZONE_ID")
-		url := os.Getenv("CF_URL")
-		if token == "" || zoneId == "" || url == "" {
-			return
-		}
+	go purgeFilesFromCDN([]File{f})
+
+	return &emptypb.Empty{}, nil
+}
 
-		// Create file URLs
-		fileUrls := []string{}
-		if f.Bucket == "icons" {
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/icons/", f.Id))
-		} else if f.Bucket == "attachments" {
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/attachments/", f.Id, "/", f.Filename))
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/attachments/", f.Id, "/", f.Filename, "?preview"))
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/attachments/", f.Id, "/", f.Filename, "?download"))
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/attachments/", f.Id, "/", f.Filename, "?preview&download"))
-			fileUrls = append(fileUrls, fmt.Sprint(url, "/attachments/", f.Id, "/", f.Filename, "?download&preview"))
+func (s grpcUploadsServer) AddReference(ctx context.Context, req *pb.AddReferenceReq) (*emptypb.Empty, error) {
+	// Get file
+	f, err := GetFile(req.Id)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
 		}
+		return nil, err
+	}
 
-		// Create body
-		jsonBody, err := json.Marshal(map[string][]string{
-			"files": fileUrls,
-		})
-		if err != nil {
+	// Check bucket
+	if f.Bucket != req.Bucket {
+		return nil, ErrMismatchedBucket
+	}
+
+	// Add reference
+	err = f.AddReference(req.GetReference().GetType(), req.GetReference().GetId())
+	if err != nil {
+		if err != ErrInvalidReference && err != mongo.ErrNoDocuments {
 			sentry.CaptureException(err)
-			return
 		}
+		return nil, err
+	}
 
-		// Create request
-		apiUrl := fmt.Sprint("https://api.cloudflare.com/client/v4/zones/", zoneId, "/purge_cache")
-		req, err := http.NewRequest(http.MethodPost, apiUrl, bytes.NewReader(jsonBody))
-		if err != nil {
+	return &emptypb.Empty{}, nil
+}
+
+func (s grpcUploadsServer) RemoveReference(ctx context.Context, req *pb.RemoveReferenceReq) (*emptypb.Empty, error) {
+	// Get file
+	f, err := GetFile(req.Id)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
 			sentry.CaptureException(err)
-			return
 		}
-		req.Header.Add("Authorization", fmt.Sprint("Bearer ", token))
+		return nil, err
+	}
 
-		// Send request
-		_, err = http.DefaultClient.Do(req)
-		if err != nil {
+	// Remove reference
+	// The file will be deleted when the cleanup thread runs if nothing else references it
+	err = f.RemoveReference(req.GetReference().GetType(), req.GetReference().GetId())
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
 			sentry.CaptureException(err)
-			return
 		}
-	}()
+		return nil, err
+	}
 
 	return &emptypb.Empty{}, nil
 }
 
 func (s grpcUploadsServer) ClearFiles(ctx context.Context, req *pb.ClearFilesReq) (*emptypb.Empty, error) {
-	// Check token
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("x-token")) == 0 || md.Get("x-token")[0] != os.Getenv("GRPC_UPLOADS_TOKEN") {
-		return nil, ErrUnauthorized
-	}
-
-	// Mark files as unclaimed
-	// Files will be deleted when the cleanup thread runs
+	// Remove post and profile references from the user's files,
+	// chat icons and emoji packs may still be using them
+	// Files will be deleted when the cleanup thread runs if nothing else references them
 	_, err := db.Collection("files").UpdateMany(
 		context.TODO(),
 		bson.M{"uploaded_by": req.UserId},
-		bson.M{"$set": bson.M{"claimed": false}},
+		bson.M{"$pull": bson.M{"references": bson.M{"type": bson.M{"$in": []string{"post", "profile", "legacy"}}}}},
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	// Remove the user's profile references from files uploaded by anyone
+	_, err = db.Collection("files").UpdateMany(
+		context.TODO(),
+		bson.M{"references": bson.M{"$elemMatch": bson.M{"type": "profile", "id": req.UserId}}},
+		bson.M{"$pull": bson.M{"references": bson.M{"type": "profile", "id": req.UserId}}},
 	)
-	return &emptypb.Empty{}, err
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s grpcUploadsServer) CreateDataExport(ctx context.Context, req *pb.CreateDataExportReq) (*pb.CreateDataExportResp, error) {
+	// Queue data export (or get the one the user already has in progress)
+	// Progress gets reported through Redis
+	id, err := CreateDataExport(req.UserId)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	return &pb.CreateDataExportResp{Id: id}, nil
+}
+
+func (s grpcUploadsServer) PurgeUserFiles(ctx context.Context, req *pb.PurgeUserFilesReq) (*pb.PurgeUserFilesResp, error) {
+	// Purge files
+	report, err := PurgeUserFiles(req.UserId)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	// Return report
+	return &pb.PurgeUserFilesResp{
+		FileIds:        report.FileIds,
+		RemovedHashes:  report.RemovedHashes,
+		RetainedHashes: report.RetainedHashes,
+		DataExportIds:  report.DataExportIds,
+	}, nil
+}
+
+func (s grpcUploadsServer) GetStorageUsage(ctx context.Context, req *pb.GetStorageUsageReq) (*pb.GetStorageUsageResp, error) {
+	// Get usage
+	buckets, err := GetStorageUsage(req.UserId)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	// Return usage
+	resp := &pb.GetStorageUsageResp{}
+	for _, bucket := range buckets {
+		resp.Buckets = append(resp.Buckets, &pb.BucketUsage{
+			Bucket: bucket.Bucket,
+			Size:   bucket.Size,
+			Files:  bucket.Files,
+		})
+		resp.TotalSize += bucket.Size
+	}
+	return resp, nil
 }
@@ -21,19 +21,162 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type FileReference struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Id   string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *FileReference) Reset() {
+	*x = FileReference{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileReference) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileReference) ProtoMessage() {}
+
+func (x *FileReference) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileReference.ProtoReflect.Descriptor instead.
+func (*FileReference) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FileReference) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *FileReference) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type AudioMetadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Duration   float64 `protobuf:"fixed64,1,opt,name=duration,proto3" json:"duration,omitempty"`
+	Codec      string  `protobuf:"bytes,2,opt,name=codec,proto3" json:"codec,omitempty"`
+	Bitrate    int64   `protobuf:"varint,3,opt,name=bitrate,proto3" json:"bitrate,omitempty"`
+	SampleRate int32   `protobuf:"varint,4,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Channels   int32   `protobuf:"varint,5,opt,name=channels,proto3" json:"channels,omitempty"`
+	Waveform   []int32 `protobuf:"varint,6,rep,packed,name=waveform,proto3" json:"waveform,omitempty"`
+}
+
+func (x *AudioMetadata) Reset() {
+	*x = AudioMetadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AudioMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AudioMetadata) ProtoMessage() {}
+
+func (x *AudioMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AudioMetadata.ProtoReflect.Descriptor instead.
+func (*AudioMetadata) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AudioMetadata) GetDuration() float64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *AudioMetadata) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *AudioMetadata) GetBitrate() int64 {
+	if x != nil {
+		return x.Bitrate
+	}
+	return 0
+}
+
+func (x *AudioMetadata) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *AudioMetadata) GetChannels() int32 {
+	if x != nil {
+		return x.Channels
+	}
+	return 0
+}
+
+func (x *AudioMetadata) GetWaveform() []int32 {
+	if x != nil {
+		return x.Waveform
+	}
+	return nil
+}
+
 type ClaimFileReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Bucket string `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Id        string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Bucket    string         `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Reference *FileReference `protobuf:"bytes,3,opt,name=reference,proto3" json:"reference,omitempty"`
 }
 
 func (x *ClaimFileReq) Reset() {
 	*x = ClaimFileReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_uploads_service_proto_msgTypes[0]
+		mi := &file_uploads_service_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -46,7 +189,7 @@ func (x *ClaimFileReq) String() string {
 func (*ClaimFileReq) ProtoMessage() {}
 
 func (x *ClaimFileReq) ProtoReflect() protoreflect.Message {
-	mi := &file_uploads_service_proto_msgTypes[0]
+	mi := &file_uploads_service_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -59,7 +202,7 @@ func (x *ClaimFileReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClaimFileReq.ProtoReflect.Descriptor instead.
 func (*ClaimFileReq) Descriptor() ([]byte, []int) {
-	return file_uploads_service_proto_rawDescGZIP(), []int{0}
+	return file_uploads_service_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ClaimFileReq) GetId() string {
@@ -76,23 +219,33 @@ func (x *ClaimFileReq) GetBucket() string {
 	return ""
 }
 
+func (x *ClaimFileReq) GetReference() *FileReference {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
 type ClaimFileResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Mime     string `protobuf:"bytes,2,opt,name=mime,proto3" json:"mime,omitempty"`
-	Filename string `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size     int64  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
-	Width    int32  `protobuf:"varint,5,opt,name=width,proto3" json:"width,omitempty"`
-	Height   int32  `protobuf:"varint,6,opt,name=height,proto3" json:"height,omitempty"`
+	Id            string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Mime          string         `protobuf:"bytes,2,opt,name=mime,proto3" json:"mime,omitempty"`
+	Filename      string         `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size          int64          `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Width         int32          `protobuf:"varint,5,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32          `protobuf:"varint,6,opt,name=height,proto3" json:"height,omitempty"`
+	Blurhash      string         `protobuf:"bytes,7,opt,name=blurhash,proto3" json:"blurhash,omitempty"`
+	DominantColor string         `protobuf:"bytes,8,opt,name=dominant_color,json=dominantColor,proto3" json:"dominant_color,omitempty"`
+	Audio         *AudioMetadata `protobuf:"bytes,9,opt,name=audio,proto3" json:"audio,omitempty"`
 }
 
 func (x *ClaimFileResp) Reset() {
 	*x = ClaimFileResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_uploads_service_proto_msgTypes[1]
+		mi := &file_uploads_service_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -105,7 +258,7 @@ func (x *ClaimFileResp) String() string {
 func (*ClaimFileResp) ProtoMessage() {}
 
 func (x *ClaimFileResp) ProtoReflect() protoreflect.Message {
-	mi := &file_uploads_service_proto_msgTypes[1]
+	mi := &file_uploads_service_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -118,7 +271,7 @@ func (x *ClaimFileResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClaimFileResp.ProtoReflect.Descriptor instead.
 func (*ClaimFileResp) Descriptor() ([]byte, []int) {
-	return file_uploads_service_proto_rawDescGZIP(), []int{1}
+	return file_uploads_service_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ClaimFileResp) GetId() string {
@@ -163,6 +316,27 @@ func (x *ClaimFileResp) GetHeight() int32 {
 	return 0
 }
 
+func (x *ClaimFileResp) GetBlurhash() string {
+	if x != nil {
+		return x.Blurhash
+	}
+	return ""
+}
+
+func (x *ClaimFileResp) GetDominantColor() string {
+	if x != nil {
+		return x.DominantColor
+	}
+	return ""
+}
+
+func (x *ClaimFileResp) GetAudio() *AudioMetadata {
+	if x != nil {
+		return x.Audio
+	}
+	return nil
+}
+
 type DeleteFileReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -174,7 +348,7 @@ type DeleteFileReq struct {
 func (x *DeleteFileReq) Reset() {
 	*x = DeleteFileReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_uploads_service_proto_msgTypes[2]
+		mi := &file_uploads_service_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -187,7 +361,7 @@ func (x *DeleteFileReq) String() string {
 func (*DeleteFileReq) ProtoMessage() {}
 
 func (x *DeleteFileReq) ProtoReflect() protoreflect.Message {
-	mi := &file_uploads_service_proto_msgTypes[2]
+	mi := &file_uploads_service_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -200,7 +374,7 @@ func (x *DeleteFileReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteFileReq.ProtoReflect.Descriptor instead.
 func (*DeleteFileReq) Descriptor() ([]byte, []int) {
-	return file_uploads_service_proto_rawDescGZIP(), []int{2}
+	return file_uploads_service_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *DeleteFileReq) GetId() string {
@@ -210,6 +384,124 @@ func (x *DeleteFileReq) GetId() string {
 	return ""
 }
 
+type AddReferenceReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id        string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Bucket    string         `protobuf:"bytes,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Reference *FileReference `protobuf:"bytes,3,opt,name=reference,proto3" json:"reference,omitempty"`
+}
+
+func (x *AddReferenceReq) Reset() {
+	*x = AddReferenceReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddReferenceReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddReferenceReq) ProtoMessage() {}
+
+func (x *AddReferenceReq) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddReferenceReq.ProtoReflect.Descriptor instead.
+func (*AddReferenceReq) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AddReferenceReq) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AddReferenceReq) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *AddReferenceReq) GetReference() *FileReference {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
+type RemoveReferenceReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id        string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Reference *FileReference `protobuf:"bytes,2,opt,name=reference,proto3" json:"reference,omitempty"`
+}
+
+func (x *RemoveReferenceReq) Reset() {
+	*x = RemoveReferenceReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RemoveReferenceReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveReferenceReq) ProtoMessage() {}
+
+func (x *RemoveReferenceReq) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveReferenceReq.ProtoReflect.Descriptor instead.
+func (*RemoveReferenceReq) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RemoveReferenceReq) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RemoveReferenceReq) GetReference() *FileReference {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
 type ClearFilesReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -221,7 +513,7 @@ type ClearFilesReq struct {
 func (x *ClearFilesReq) Reset() {
 	*x = ClearFilesReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_uploads_service_proto_msgTypes[3]
+		mi := &file_uploads_service_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -234,7 +526,7 @@ func (x *ClearFilesReq) String() string {
 func (*ClearFilesReq) ProtoMessage() {}
 
 func (x *ClearFilesReq) ProtoReflect() protoreflect.Message {
-	mi := &file_uploads_service_proto_msgTypes[3]
+	mi := &file_uploads_service_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -247,7 +539,7 @@ func (x *ClearFilesReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearFilesReq.ProtoReflect.Descriptor instead.
 func (*ClearFilesReq) Descriptor() ([]byte, []int) {
-	return file_uploads_service_proto_rawDescGZIP(), []int{3}
+	return file_uploads_service_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ClearFilesReq) GetUserId() string {
@@ -257,88 +549,585 @@ func (x *ClearFilesReq) GetUserId() string {
 	return ""
 }
 
-var File_uploads_service_proto protoreflect.FileDescriptor
+type CreateDataExportReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_uploads_service_proto_rawDesc = []byte{
-	0x0a, 0x15, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73,
-	0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x36, 0x0a,
-	0x0c, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a,
-	0x06, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x62,
-	0x75, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x91, 0x01, 0x0a, 0x0d, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46,
-	0x69, 0x6c, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x69, 0x6d, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66,
-	0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66,
-	0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x77,
-	0x69, 0x64, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77, 0x69, 0x64, 0x74,
-	0x68, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22, 0x1f, 0x0a, 0x0d, 0x44, 0x65, 0x6c,
-	0x65, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x28, 0x0a, 0x0d, 0x43, 0x6c,
-	0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x12, 0x17, 0x0a, 0x07, 0x75,
-	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73,
-	0x65, 0x72, 0x49, 0x64, 0x32, 0xc1, 0x01, 0x0a, 0x07, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73,
-	0x12, 0x3a, 0x0a, 0x09, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x15, 0x2e,
-	0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c,
-	0x65, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43,
-	0x6c, 0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x3c, 0x0a, 0x0a,
-	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x16, 0x2e, 0x75, 0x70, 0x6c,
-	0x6f, 0x61, 0x64, 0x73, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x52,
-	0x65, 0x71, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x3c, 0x0a, 0x0a, 0x43, 0x6c,
-	0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61,
-	0x64, 0x73, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71,
-	0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
-	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 }
 
-var (
-	file_uploads_service_proto_rawDescOnce sync.Once
-	file_uploads_service_proto_rawDescData = file_uploads_service_proto_rawDesc
-)
-
-func file_uploads_service_proto_rawDescGZIP() []byte {
-	file_uploads_service_proto_rawDescOnce.Do(func() {
-		file_uploads_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_uploads_service_proto_rawDescData)
-	})
-	return file_uploads_service_proto_rawDescData
+func (x *CreateDataExportReq) Reset() {
+	*x = CreateDataExportReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-var file_uploads_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_uploads_service_proto_goTypes = []interface{}{
-	(*ClaimFileReq)(nil),  // 0: uploads.ClaimFileReq
-	(*ClaimFileResp)(nil), // 1: uploads.ClaimFileResp
-	(*DeleteFileReq)(nil), // 2: uploads.DeleteFileReq
-	(*ClearFilesReq)(nil), // 3: uploads.ClearFilesReq
-	(*emptypb.Empty)(nil), // 4: google.protobuf.Empty
-}
-var file_uploads_service_proto_depIdxs = []int32{
-	0, // 0: uploads.Uploads.ClaimFile:input_type -> uploads.ClaimFileReq
-	2, // 1: uploads.Uploads.DeleteFile:input_type -> uploads.DeleteFileReq
-	3, // 2: uploads.Uploads.ClearFiles:input_type -> uploads.ClearFilesReq
-	1, // 3: uploads.Uploads.ClaimFile:output_type -> uploads.ClaimFileResp
-	4, // 4: uploads.Uploads.DeleteFile:output_type -> google.protobuf.Empty
-	4, // 5: uploads.Uploads.ClearFiles:output_type -> google.protobuf.Empty
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+func (x *CreateDataExportReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_uploads_service_proto_init() }
-func file_uploads_service_proto_init() {
+func (*CreateDataExportReq) ProtoMessage() {}
+
+func (x *CreateDataExportReq) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDataExportReq.ProtoReflect.Descriptor instead.
+func (*CreateDataExportReq) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateDataExportReq) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type CreateDataExportResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *CreateDataExportResp) Reset() {
+	*x = CreateDataExportResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateDataExportResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDataExportResp) ProtoMessage() {}
+
+func (x *CreateDataExportResp) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDataExportResp.ProtoReflect.Descriptor instead.
+func (*CreateDataExportResp) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateDataExportResp) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PurgeUserFilesReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+}
+
+func (x *PurgeUserFilesReq) Reset() {
+	*x = PurgeUserFilesReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PurgeUserFilesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeUserFilesReq) ProtoMessage() {}
+
+func (x *PurgeUserFilesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeUserFilesReq.ProtoReflect.Descriptor instead.
+func (*PurgeUserFilesReq) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PurgeUserFilesReq) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type PurgeUserFilesResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FileIds        []string `protobuf:"bytes,1,rep,name=file_ids,json=fileIds,proto3" json:"file_ids,omitempty"`
+	RemovedHashes  []string `protobuf:"bytes,2,rep,name=removed_hashes,json=removedHashes,proto3" json:"removed_hashes,omitempty"`
+	RetainedHashes []string `protobuf:"bytes,3,rep,name=retained_hashes,json=retainedHashes,proto3" json:"retained_hashes,omitempty"`
+	DataExportIds  []string `protobuf:"bytes,4,rep,name=data_export_ids,json=dataExportIds,proto3" json:"data_export_ids,omitempty"`
+}
+
+func (x *PurgeUserFilesResp) Reset() {
+	*x = PurgeUserFilesResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PurgeUserFilesResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeUserFilesResp) ProtoMessage() {}
+
+func (x *PurgeUserFilesResp) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeUserFilesResp.ProtoReflect.Descriptor instead.
+func (*PurgeUserFilesResp) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PurgeUserFilesResp) GetFileIds() []string {
+	if x != nil {
+		return x.FileIds
+	}
+	return nil
+}
+
+func (x *PurgeUserFilesResp) GetRemovedHashes() []string {
+	if x != nil {
+		return x.RemovedHashes
+	}
+	return nil
+}
+
+func (x *PurgeUserFilesResp) GetRetainedHashes() []string {
+	if x != nil {
+		return x.RetainedHashes
+	}
+	return nil
+}
+
+func (x *PurgeUserFilesResp) GetDataExportIds() []string {
+	if x != nil {
+		return x.DataExportIds
+	}
+	return nil
+}
+
+type GetStorageUsageReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+}
+
+func (x *GetStorageUsageReq) Reset() {
+	*x = GetStorageUsageReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetStorageUsageReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStorageUsageReq) ProtoMessage() {}
+
+func (x *GetStorageUsageReq) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStorageUsageReq.ProtoReflect.Descriptor instead.
+func (*GetStorageUsageReq) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetStorageUsageReq) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type BucketUsage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bucket string `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Size   int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Files  int64  `protobuf:"varint,3,opt,name=files,proto3" json:"files,omitempty"`
+}
+
+func (x *BucketUsage) Reset() {
+	*x = BucketUsage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BucketUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BucketUsage) ProtoMessage() {}
+
+func (x *BucketUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BucketUsage.ProtoReflect.Descriptor instead.
+func (*BucketUsage) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BucketUsage) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *BucketUsage) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *BucketUsage) GetFiles() int64 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+type GetStorageUsageResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Buckets   []*BucketUsage `protobuf:"bytes,1,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	TotalSize int64          `protobuf:"varint,2,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+}
+
+func (x *GetStorageUsageResp) Reset() {
+	*x = GetStorageUsageResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_uploads_service_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetStorageUsageResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStorageUsageResp) ProtoMessage() {}
+
+func (x *GetStorageUsageResp) ProtoReflect() protoreflect.Message {
+	mi := &file_uploads_service_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStorageUsageResp.ProtoReflect.Descriptor instead.
+func (*GetStorageUsageResp) Descriptor() ([]byte, []int) {
+	return file_uploads_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetStorageUsageResp) GetBuckets() []*BucketUsage {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+func (x *GetStorageUsageResp) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+var File_uploads_service_proto protoreflect.FileDescriptor
+
+var file_uploads_service_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73,
+	0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x33, 0x0a,
+	0x0d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x69, 0x64, 0x22, 0xb4, 0x01, 0x0a, 0x0d, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x4d, 0x65, 0x74, 0x61,
+	0x64, 0x61, 0x74, 0x61, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x69, 0x74, 0x72, 0x61, 0x74,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x62, 0x69, 0x74, 0x72, 0x61, 0x74, 0x65,
+	0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52, 0x61, 0x74,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x1a, 0x0a,
+	0x08, 0x77, 0x61, 0x76, 0x65, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x06, 0x20, 0x03, 0x28, 0x05, 0x52,
+	0x08, 0x77, 0x61, 0x76, 0x65, 0x66, 0x6f, 0x72, 0x6d, 0x22, 0x6c, 0x0a, 0x0c, 0x43, 0x6c, 0x61,
+	0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x63,
+	0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x62, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x12, 0x34, 0x0a, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x46,
+	0x69, 0x6c, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x09, 0x72, 0x65,
+	0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x22, 0x82, 0x02, 0x0a, 0x0d, 0x43, 0x6c, 0x61, 0x69,
+	0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x69, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77, 0x69,
+	0x64, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x62,
+	0x6c, 0x75, 0x72, 0x68, 0x61, 0x73, 0x68, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62,
+	0x6c, 0x75, 0x72, 0x68, 0x61, 0x73, 0x68, 0x12, 0x25, 0x0a, 0x0e, 0x64, 0x6f, 0x6d, 0x69, 0x6e,
+	0x61, 0x6e, 0x74, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x64, 0x6f, 0x6d, 0x69, 0x6e, 0x61, 0x6e, 0x74, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x2c,
+	0x0a, 0x05, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x05, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x22, 0x1f, 0x0a, 0x0d,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x6f, 0x0a,
+	0x0f, 0x41, 0x64, 0x64, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x34, 0x0a, 0x09, 0x72, 0x65, 0x66, 0x65,
+	0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x75, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65,
+	0x6e, 0x63, 0x65, 0x52, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x22, 0x5a,
+	0x0a, 0x12, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63,
+	0x65, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x34, 0x0a, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x73, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52,
+	0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x22, 0x28, 0x0a, 0x0d, 0x43, 0x6c,
+	0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x12, 0x17, 0x0a, 0x07, 0x75,
+	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73,
+	0x65, 0x72, 0x49, 0x64, 0x22, 0x2e, 0x0a, 0x13, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x44, 0x61,
+	0x74, 0x61, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x12, 0x17, 0x0a, 0x07, 0x75,
+	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73,
+	0x65, 0x72, 0x49, 0x64, 0x22, 0x26, 0x0a, 0x14, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x44, 0x61,
+	0x74, 0x61, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x2c, 0x0a, 0x11,
+	0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65,
+	0x71, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x22, 0xa7, 0x01, 0x0a, 0x12, 0x50,
+	0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x07, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x0e,
+	0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x48, 0x61, 0x73,
+	0x68, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x72, 0x65, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x64, 0x5f,
+	0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x72, 0x65,
+	0x74, 0x61, 0x69, 0x6e, 0x65, 0x64, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0f,
+	0x64, 0x61, 0x74, 0x61, 0x5f, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x69, 0x64, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x64, 0x61, 0x74, 0x61, 0x45, 0x78, 0x70, 0x6f, 0x72,
+	0x74, 0x49, 0x64, 0x73, 0x22, 0x2d, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x53, 0x74, 0x6f, 0x72, 0x61,
+	0x67, 0x65, 0x55, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73,
+	0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65,
+	0x72, 0x49, 0x64, 0x22, 0x4f, 0x0a, 0x0b, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x55, 0x73, 0x61,
+	0x67, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x66,
+	0x69, 0x6c, 0x65, 0x73, 0x22, 0x64, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x53, 0x74, 0x6f, 0x72, 0x61,
+	0x67, 0x65, 0x55, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2e, 0x0a, 0x07, 0x62,
+	0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x75,
+	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x55, 0x73, 0x61,
+	0x67, 0x65, 0x52, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x74,
+	0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x53, 0x69, 0x7a, 0x65, 0x32, 0xb5, 0x04, 0x0a, 0x07, 0x55,
+	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x12, 0x3a, 0x0a, 0x09, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46,
+	0x69, 0x6c, 0x65, 0x12, 0x15, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43, 0x6c,
+	0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x70, 0x6c,
+	0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x12, 0x3c, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x12, 0x40, 0x0a, 0x0c, 0x41, 0x64, 0x64, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x18, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65,
+	0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70,
+	0x74, 0x79, 0x12, 0x46, 0x0a, 0x0f, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x66, 0x65,
+	0x72, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1b, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e,
+	0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52,
+	0x65, 0x71, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x3c, 0x0a, 0x0a, 0x43, 0x6c,
+	0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x16, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61,
+	0x64, 0x73, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x4f, 0x0a, 0x10, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x44, 0x61, 0x74, 0x61, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x1c, 0x2e, 0x75,
+	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x44, 0x61, 0x74,
+	0x61, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x1d, 0x2e, 0x75, 0x70, 0x6c,
+	0x6f, 0x61, 0x64, 0x73, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x44, 0x61, 0x74, 0x61, 0x45,
+	0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x49, 0x0a, 0x0e, 0x50, 0x75, 0x72,
+	0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x75, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x46,
+	0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x1b, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x73, 0x2e, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x12, 0x4c, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x53, 0x74, 0x6f, 0x72, 0x61,
+	0x67, 0x65, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1b, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x73, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x55, 0x73, 0x61, 0x67,
+	0x65, 0x52, 0x65, 0x71, 0x1a, 0x1c, 0x2e, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x2e, 0x47,
+	0x65, 0x74, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x55, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_uploads_service_proto_rawDescOnce sync.Once
+	file_uploads_service_proto_rawDescData = file_uploads_service_proto_rawDesc
+)
+
+func file_uploads_service_proto_rawDescGZIP() []byte {
+	file_uploads_service_proto_rawDescOnce.Do(func() {
+		file_uploads_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_uploads_service_proto_rawDescData)
+	})
+	return file_uploads_service_proto_rawDescData
+}
+
+var file_uploads_service_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_uploads_service_proto_goTypes = []interface{}{
+	(*FileReference)(nil),        // 0: uploads.FileReference
+	(*AudioMetadata)(nil),        // 1: uploads.AudioMetadata
+	(*ClaimFileReq)(nil),         // 2: uploads.ClaimFileReq
+	(*ClaimFileResp)(nil),        // 3: uploads.ClaimFileResp
+	(*DeleteFileReq)(nil),        // 4: uploads.DeleteFileReq
+	(*AddReferenceReq)(nil),      // 5: uploads.AddReferenceReq
+	(*RemoveReferenceReq)(nil),   // 6: uploads.RemoveReferenceReq
+	(*ClearFilesReq)(nil),        // 7: uploads.ClearFilesReq
+	(*CreateDataExportReq)(nil),  // 8: uploads.CreateDataExportReq
+	(*CreateDataExportResp)(nil), // 9: uploads.CreateDataExportResp
+	(*PurgeUserFilesReq)(nil),    // 10: uploads.PurgeUserFilesReq
+	(*PurgeUserFilesResp)(nil),   // 11: uploads.PurgeUserFilesResp
+	(*GetStorageUsageReq)(nil),   // 12: uploads.GetStorageUsageReq
+	(*BucketUsage)(nil),          // 13: uploads.BucketUsage
+	(*GetStorageUsageResp)(nil),  // 14: uploads.GetStorageUsageResp
+	(*emptypb.Empty)(nil),        // 15: google.protobuf.Empty
+}
+var file_uploads_service_proto_depIdxs = []int32{
+	0,  // 0: uploads.ClaimFileReq.reference:type_name -> uploads.FileReference
+	1,  // 1: uploads.ClaimFileResp.audio:type_name -> uploads.AudioMetadata
+	0,  // 2: uploads.AddReferenceReq.reference:type_name -> uploads.FileReference
+	0,  // 3: uploads.RemoveReferenceReq.reference:type_name -> uploads.FileReference
+	13, // 4: uploads.GetStorageUsageResp.buckets:type_name -> uploads.BucketUsage
+	2,  // 5: uploads.Uploads.ClaimFile:input_type -> uploads.ClaimFileReq
+	4,  // 6: uploads.Uploads.DeleteFile:input_type -> uploads.DeleteFileReq
+	5,  // 7: uploads.Uploads.AddReference:input_type -> uploads.AddReferenceReq
+	6,  // 8: uploads.Uploads.RemoveReference:input_type -> uploads.RemoveReferenceReq
+	7,  // 9: uploads.Uploads.ClearFiles:input_type -> uploads.ClearFilesReq
+	8,  // 10: uploads.Uploads.CreateDataExport:input_type -> uploads.CreateDataExportReq
+	10, // 11: uploads.Uploads.PurgeUserFiles:input_type -> uploads.PurgeUserFilesReq
+	12, // 12: uploads.Uploads.GetStorageUsage:input_type -> uploads.GetStorageUsageReq
+	3,  // 13: uploads.Uploads.ClaimFile:output_type -> uploads.ClaimFileResp
+	15, // 14: uploads.Uploads.DeleteFile:output_type -> google.protobuf.Empty
+	15, // 15: uploads.Uploads.AddReference:output_type -> google.protobuf.Empty
+	15, // 16: uploads.Uploads.RemoveReference:output_type -> google.protobuf.Empty
+	15, // 17: uploads.Uploads.ClearFiles:output_type -> google.protobuf.Empty
+	9,  // 18: uploads.Uploads.CreateDataExport:output_type -> uploads.CreateDataExportResp
+	11, // 19: uploads.Uploads.PurgeUserFiles:output_type -> uploads.PurgeUserFilesResp
+	14, // 20: uploads.Uploads.GetStorageUsage:output_type -> uploads.GetStorageUsageResp
+	13, // [13:21] is the sub-list for method output_type
+	5,  // [5:13] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_uploads_service_proto_init() }
+func file_uploads_service_proto_init() {
 	if File_uploads_service_proto != nil {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_uploads_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ClaimFileReq); i {
+			switch v := v.(*FileReference); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -350,7 +1139,7 @@ func file_uploads_service_proto_init() {
 			}
 		}
 		file_uploads_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ClaimFileResp); i {
+			switch v := v.(*AudioMetadata); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -362,7 +1151,7 @@ func file_uploads_service_proto_init() {
 			}
 		}
 		file_uploads_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeleteFileReq); i {
+			switch v := v.(*ClaimFileReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -374,6 +1163,54 @@ func file_uploads_service_proto_init() {
 			}
 		}
 		file_uploads_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimFileResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteFileReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddReferenceReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RemoveReferenceReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ClearFilesReq); i {
 			case 0:
 				return &v.state
@@ -385,6 +1222,90 @@ func file_uploads_service_proto_init() {
 				return nil
 			}
 		}
+		file_uploads_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateDataExportReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateDataExportResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PurgeUserFilesReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PurgeUserFilesResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetStorageUsageReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BucketUsage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_uploads_service_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetStorageUsageResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -392,7 +1313,7 @@ func file_uploads_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_uploads_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
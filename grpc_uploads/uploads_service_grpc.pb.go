@@ -20,9 +20,14 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Uploads_ClaimFile_FullMethodName  = "/uploads.Uploads/ClaimFile"
-	Uploads_DeleteFile_FullMethodName = "/uploads.Uploads/DeleteFile"
-	Uploads_ClearFiles_FullMethodName = "/uploads.Uploads/ClearFiles"
+	Uploads_ClaimFile_FullMethodName        = "/uploads.Uploads/ClaimFile"
+	Uploads_DeleteFile_FullMethodName       = "/uploads.Uploads/DeleteFile"
+	Uploads_AddReference_FullMethodName     = "/uploads.Uploads/AddReference"
+	Uploads_RemoveReference_FullMethodName  = "/uploads.Uploads/RemoveReference"
+	Uploads_ClearFiles_FullMethodName       = "/uploads.Uploads/ClearFiles"
+	Uploads_CreateDataExport_FullMethodName = "/uploads.Uploads/CreateDataExport"
+	Uploads_PurgeUserFiles_FullMethodName   = "/uploads.Uploads/PurgeUserFiles"
+	Uploads_GetStorageUsage_FullMethodName  = "/uploads.Uploads/GetStorageUsage"
 )
 
 // UploadsClient is the client API for Uploads service.
@@ -33,8 +38,18 @@ type UploadsClient interface {
 	ClaimFile(ctx context.Context, in *ClaimFileReq, opts ...grpc.CallOption) (*ClaimFileResp, error)
 	// Delete a file
 	DeleteFile(ctx context.Context, in *DeleteFileReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Add a reference to a file
+	AddReference(ctx context.Context, in *AddReferenceReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Remove a reference from a file
+	RemoveReference(ctx context.Context, in *RemoveReferenceReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// Clear a user's files
 	ClearFiles(ctx context.Context, in *ClearFilesReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Start generating a data export of a user's files
+	CreateDataExport(ctx context.Context, in *CreateDataExportReq, opts ...grpc.CallOption) (*CreateDataExportResp, error)
+	// Delete all of a user's files and their objects
+	PurgeUserFiles(ctx context.Context, in *PurgeUserFilesReq, opts ...grpc.CallOption) (*PurgeUserFilesResp, error)
+	// Get a user's storage usage
+	GetStorageUsage(ctx context.Context, in *GetStorageUsageReq, opts ...grpc.CallOption) (*GetStorageUsageResp, error)
 }
 
 type uploadsClient struct {
@@ -63,6 +78,24 @@ func (c *uploadsClient) DeleteFile(ctx context.Context, in *DeleteFileReq, opts
 	return out, nil
 }
 
+func (c *uploadsClient) AddReference(ctx context.Context, in *AddReferenceReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Uploads_AddReference_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadsClient) RemoveReference(ctx context.Context, in *RemoveReferenceReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Uploads_RemoveReference_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *uploadsClient) ClearFiles(ctx context.Context, in *ClearFilesReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, Uploads_ClearFiles_FullMethodName, in, out, opts...)
@@ -72,6 +105,33 @@ func (c *uploadsClient) ClearFiles(ctx context.Context, in *ClearFilesReq, opts
 	return out, nil
 }
 
+func (c *uploadsClient) CreateDataExport(ctx context.Context, in *CreateDataExportReq, opts ...grpc.CallOption) (*CreateDataExportResp, error) {
+	out := new(CreateDataExportResp)
+	err := c.cc.Invoke(ctx, Uploads_CreateDataExport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadsClient) PurgeUserFiles(ctx context.Context, in *PurgeUserFilesReq, opts ...grpc.CallOption) (*PurgeUserFilesResp, error) {
+	out := new(PurgeUserFilesResp)
+	err := c.cc.Invoke(ctx, Uploads_PurgeUserFiles_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadsClient) GetStorageUsage(ctx context.Context, in *GetStorageUsageReq, opts ...grpc.CallOption) (*GetStorageUsageResp, error) {
+	out := new(GetStorageUsageResp)
+	err := c.cc.Invoke(ctx, Uploads_GetStorageUsage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UploadsServer is the server API for Uploads service.
 // All implementations must embed UnimplementedUploadsServer
 // for forward compatibility
@@ -80,8 +140,18 @@ type UploadsServer interface {
 	ClaimFile(context.Context, *ClaimFileReq) (*ClaimFileResp, error)
 	// Delete a file
 	DeleteFile(context.Context, *DeleteFileReq) (*emptypb.Empty, error)
+	// Add a reference to a file
+	AddReference(context.Context, *AddReferenceReq) (*emptypb.Empty, error)
+	// Remove a reference from a file
+	RemoveReference(context.Context, *RemoveReferenceReq) (*emptypb.Empty, error)
 	// Clear a user's files
 	ClearFiles(context.Context, *ClearFilesReq) (*emptypb.Empty, error)
+	// Start generating a data export of a user's files
+	CreateDataExport(context.Context, *CreateDataExportReq) (*CreateDataExportResp, error)
+	// Delete all of a user's files and their objects
+	PurgeUserFiles(context.Context, *PurgeUserFilesReq) (*PurgeUserFilesResp, error)
+	// Get a user's storage usage
+	GetStorageUsage(context.Context, *GetStorageUsageReq) (*GetStorageUsageResp, error)
 	mustEmbedUnimplementedUploadsServer()
 }
 
@@ -95,9 +165,24 @@ func (UnimplementedUploadsServer) ClaimFile(context.Context, *ClaimFileReq) (*Cl
 func (UnimplementedUploadsServer) DeleteFile(context.Context, *DeleteFileReq) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteFile not implemented")
 }
+func (UnimplementedUploadsServer) AddReference(context.Context, *AddReferenceReq) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddReference not implemented")
+}
+func (UnimplementedUploadsServer) RemoveReference(context.Context, *RemoveReferenceReq) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveReference not implemented")
+}
 func (UnimplementedUploadsServer) ClearFiles(context.Context, *ClearFilesReq) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ClearFiles not implemented")
 }
+func (UnimplementedUploadsServer) CreateDataExport(context.Context, *CreateDataExportReq) (*CreateDataExportResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDataExport not implemented")
+}
+func (UnimplementedUploadsServer) PurgeUserFiles(context.Context, *PurgeUserFilesReq) (*PurgeUserFilesResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeUserFiles not implemented")
+}
+func (UnimplementedUploadsServer) GetStorageUsage(context.Context, *GetStorageUsageReq) (*GetStorageUsageResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStorageUsage not implemented")
+}
 func (UnimplementedUploadsServer) mustEmbedUnimplementedUploadsServer() {}
 
 // UnsafeUploadsServer may be embedded to opt out of forward compatibility for this service.
@@ -147,6 +232,42 @@ func _Uploads_DeleteFile_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Uploads_AddReference_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddReferenceReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadsServer).AddReference(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uploads_AddReference_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadsServer).AddReference(ctx, req.(*AddReferenceReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uploads_RemoveReference_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveReferenceReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadsServer).RemoveReference(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uploads_RemoveReference_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadsServer).RemoveReference(ctx, req.(*RemoveReferenceReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Uploads_ClearFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ClearFilesReq)
 	if err := dec(in); err != nil {
@@ -165,6 +286,60 @@ func _Uploads_ClearFiles_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Uploads_CreateDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDataExportReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadsServer).CreateDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uploads_CreateDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadsServer).CreateDataExport(ctx, req.(*CreateDataExportReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uploads_PurgeUserFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeUserFilesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadsServer).PurgeUserFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uploads_PurgeUserFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadsServer).PurgeUserFiles(ctx, req.(*PurgeUserFilesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uploads_GetStorageUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStorageUsageReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadsServer).GetStorageUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uploads_GetStorageUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadsServer).GetStorageUsage(ctx, req.(*GetStorageUsageReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Uploads_ServiceDesc is the grpc.ServiceDesc for Uploads service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -180,10 +355,30 @@ var Uploads_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteFile",
 			Handler:    _Uploads_DeleteFile_Handler,
 		},
+		{
+			MethodName: "AddReference",
+			Handler:    _Uploads_AddReference_Handler,
+		},
+		{
+			MethodName: "RemoveReference",
+			Handler:    _Uploads_RemoveReference_Handler,
+		},
 		{
 			MethodName: "ClearFiles",
 			Handler:    _Uploads_ClearFiles_Handler,
 		},
+		{
+			MethodName: "CreateDataExport",
+			Handler:    _Uploads_CreateDataExport_Handler,
+		},
+		{
+			MethodName: "PurgeUserFiles",
+			Handler:    _Uploads_PurgeUserFiles_Handler,
+		},
+		{
+			MethodName: "GetStorageUsage",
+			Handler:    _Uploads_GetStorageUsage_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "uploads_service.proto",
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/minio/minio-go/v7"
+)
+
+// Target length of HLS segments, in seconds
+const hlsSegmentDuration = 6
+
+const hlsPlaylistName = "playlist.m3u8"
+
+var hlsFileNameRegex = regexp.MustCompile(`^(playlist\.m3u8|segment[0-9]{3,}\.ts)$`)
+
+// Key of a file in the HLS package of a video
+func (f *File) hlsKey(name string) string {
+	return f.Hash + ".hls/" + name
+}
+
+// Segment an H.264/AAC MP4 into an HLS playlist and segments, and put them in the local region.
+// Returns the number of segments.
+func packageHLS(f File, mp4Path string) (int, error) {
+	dir, err := os.MkdirTemp("", "meower-uploads-hls-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	// Segment video
+	if _, err := runCommand(
+		time.Minute*5,
+		nil,
+		"ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", mp4Path,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "segment%03d.ts"),
+		filepath.Join(dir, hlsPlaylistName),
+	); err != nil {
+		return 0, err
+	}
+
+	// Put segments, then the playlist once every segment exists
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	segments := 0
+	for _, entry := range entries {
+		if entry.Name() == hlsPlaylistName || !hlsFileNameRegex.MatchString(entry.Name()) {
+			continue
+		}
+		if _, err := s3Clients[s3RegionOrder[0]].FPutObject(
+			ctx,
+			f.Bucket,
+			f.hlsKey(entry.Name()),
+			filepath.Join(dir, entry.Name()),
+			minio.PutObjectOptions{
+				ContentType: "video/mp2t",
+			},
+		); err != nil {
+			return 0, err
+		}
+		segments++
+	}
+	if _, err := s3Clients[s3RegionOrder[0]].FPutObject(
+		ctx,
+		f.Bucket,
+		f.hlsKey(hlsPlaylistName),
+		filepath.Join(dir, hlsPlaylistName),
+		minio.PutObjectOptions{
+			ContentType: "application/vnd.apple.mpegurl",
+		},
+	); err != nil {
+		return 0, err
+	}
+
+	return segments, nil
+}
+
+func downloadHLS(w http.ResponseWriter, r *http.Request) {
+	// Get file
+	f, ok := getDownloadFile(w, r)
+	if !ok {
+		return
+	}
+	name := chi.URLParam(r, "*")
+	if f.Transcode == nil || !f.Transcode.HLS || !hlsFileNameRegex.MatchString(name) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	etag := f.Id + "-hls-" + name
+	if checkDownloadNotModified(w, r, etag) {
+		return
+	}
+
+	// Get object
+	obj, objInfo, err := f.getObject(f.hlsKey(name), f.Transcode.Region)
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to get object", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+	var body io.Reader = obj
+	size := objInfo.Size
+
+	// Pass the token on to segments of private files, since players only send it for the playlist
+	if name == hlsPlaylistName && r.URL.Query().Get("t") != "" {
+		var playlist bytes.Buffer
+		scanner := bufio.NewScanner(obj)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line != "" && !strings.HasPrefix(line, "#") {
+				line += "?t=" + url.QueryEscape(r.URL.Query().Get("t"))
+			}
+			playlist.WriteString(line + "\n")
+		}
+		if err := scanner.Err(); err != nil {
+			sentry.CaptureException(err)
+			http.Error(w, "Failed to get object", http.StatusInternalServerError)
+			return
+		}
+		body, size = &playlist, int64(playlist.Len())
+	}
+
+	// Set response headers
+	w.Header().Set("Content-Type", objInfo.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	setDownloadCacheHeaders(w, f, etag)
+
+	// Copy the object data into the response body
+	if _, err := io.Copy(w, body); err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to send object", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Get the URLs of every file in the HLS package of a video, for purging them from the CDN
+func hlsFileUrls(baseUrl string, f File) []string {
+	if f.Transcode == nil || !f.Transcode.HLS {
+		return nil
+	}
+	urls := []string{fmt.Sprint(baseUrl, "/", f.Bucket, "/", f.Id, "/hls/", hlsPlaylistName)}
+	for i := 0; i < f.Transcode.HLSSegments; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%s/%s/hls/segment%03d.ts", baseUrl, f.Bucket, f.Id, i))
+	}
+	return urls
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// How long responses are kept for retries with the same Idempotency-Key
+const idempotencyKeyTTL = time.Hour * 24
+
+// How long a request can be processing for before retries with the same Idempotency-Key
+// are processed again (in case the original request never finished, e.g. from a crash)
+const idempotencyPendingTTL = time.Minute * 5
+
+// What's stored under an Idempotency-Key
+type idempotentResponse struct {
+	Fingerprint string          `json:"fingerprint"`    // hash of the request payload
+	Body        json.RawMessage `json:"body,omitempty"` // empty while the original request is still being processed
+}
+
+// Start handling a request with an Idempotency-Key header.
+// Returns the Redis key to store the response under (empty if the request has no Idempotency-Key),
+// and the original response if the request has already been handled.
+// Returns ErrIdempotencyKeyReused if the key was used for a request with a different payload.
+func beginIdempotentRequest(r *http.Request, userId string, fingerprint string) (string, []byte, error) {
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		return "", nil, nil
+	}
+	if len(idempotencyKey) > 255 {
+		return "", nil, ErrInvalidIdempotencyKey
+	}
+	key := "upload_idempotency:" + userId + ":" + r.URL.Path + ":" + idempotencyKey
+
+	// Claim the key, or get the original response
+	pending, err := json.Marshal(idempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return "", nil, err
+	}
+	claimed, err := rdb.SetNX(ctx, key, pending, idempotencyPendingTTL).Result()
+	if err != nil {
+		return "", nil, err
+	}
+	if claimed {
+		return key, nil, nil
+	}
+	encoded, err := rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		// Original request failed in the meantime, so try again
+		return beginIdempotentRequest(r, userId, fingerprint)
+	} else if err != nil {
+		return "", nil, err
+	}
+	var resp idempotentResponse
+	if err := json.Unmarshal(encoded, &resp); err != nil {
+		return "", nil, err
+	}
+	if resp.Fingerprint != fingerprint {
+		return "", nil, ErrIdempotencyKeyReused
+	}
+	if len(resp.Body) == 0 {
+		return "", nil, ErrRequestInProgress
+	}
+	return key, resp.Body, nil
+}
+
+// Save the response for retries with the same Idempotency-Key
+func finishIdempotentRequest(key string, fingerprint string, body []byte) error {
+	if key == "" {
+		return nil
+	}
+	encoded, err := json.Marshal(idempotentResponse{
+		Fingerprint: fingerprint,
+		Body:        body,
+	})
+	if err != nil {
+		return err
+	}
+	return rdb.Set(ctx, key, encoded, idempotencyKeyTTL).Err()
+}
+
+// Allow retries with the same Idempotency-Key to be processed again
+func abortIdempotentRequest(key string) error {
+	if key == "" {
+		return nil
+	}
+	return rdb.Del(ctx, key).Err()
+}
+
+// Get a hash of the files and form values of an upload, to tell whether a retry is for the same upload
+func getUploadFingerprint(form *multipart.Form) (string, error) {
+	h := sha256.New()
+	for _, header := range form.File["file"] {
+		file, err := header.Open()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file:%q:%d:", header.Filename, header.Size)
+		_, err = io.Copy(h, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, name := range []string{"crop_x", "crop_y", "crop_width", "crop_height", "focal_x", "focal_y"} {
+		fmt.Fprintf(h, "%s:%q:", name, form.Value[name])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"crypto/tls"
@@ -23,6 +24,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
+	channelzService "google.golang.org/grpc/channelz/service"
+	grpcCredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	grpcUploads "github.com/meower-media-co/Meower-Uploads/grpc_uploads"
@@ -106,12 +111,41 @@ func main() {
 		s3RegionOrder = append(s3RegionOrder, name)
 	}
 
+	// Load bucket registry
+	if err := loadBuckets(); err != nil {
+		log.Fatalln(err)
+	}
+	if err := ensureBuckets(); err != nil {
+		log.Println(err)
+		sentry.CaptureException(err)
+	}
+
+	// Start video transcoding workers
+	transcodeWorkers := 1
+	if os.Getenv("TRANSCODE_WORKERS") != "" {
+		transcodeWorkers, err = strconv.Atoi(os.Getenv("TRANSCODE_WORKERS"))
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	for i := 0; i < transcodeWorkers; i++ {
+		go runTranscodeWorker()
+	}
+
+	// Start data export worker
+	go runDataExportWorker()
+
 	if os.Getenv("PRIMARY_NODE") == "1" {
 		/*/ Run migrations
 		if err := runMigrations(); err != nil {
 			log.Fatalln(err)
 		}*/
 
+		// Convert claimed files into references
+		if err := migrateClaimedFiles(); err != nil {
+			log.Fatalln(err)
+		}
+
 		// Files cleanup
 		go func() {
 			for {
@@ -122,14 +156,67 @@ func main() {
 			}
 		}()
 
+		// Storage usage rollup
+		go func() {
+			for {
+				if err := rollupStorageUsage(); err != nil {
+					sentry.CaptureException(err)
+				}
+				time.Sleep(time.Minute * 10)
+			}
+		}()
+
+		// Lost transcoding jobs
+		go func() {
+			for {
+				time.Sleep(time.Minute * 10)
+				if err := requeueStaleTranscodes(); err != nil {
+					sentry.CaptureException(err)
+				}
+			}
+		}()
+
+		// Lost data export jobs
+		go func() {
+			for {
+				time.Sleep(time.Minute * 10)
+				if err := requeueStaleDataExports(); err != nil {
+					sentry.CaptureException(err)
+				}
+			}
+		}()
+
 		// Start gRPC Uploads service
 		go func() {
 			lis, err := net.Listen("tcp", os.Getenv("GRPC_UPLOADS_ADDRESS"))
 			if err != nil {
 				log.Fatalln(err)
 			}
-			s := grpc.NewServer()
+			serverOpts := []grpc.ServerOption{
+				grpc.UnaryInterceptor(grpcUnaryInterceptor),
+				grpc.StreamInterceptor(grpcStreamInterceptor),
+			}
+			tlsConfig, err := loadGrpcTLSConfig()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if tlsConfig != nil {
+				serverOpts = append(serverOpts, grpc.Creds(grpcCredentials.NewTLS(tlsConfig)))
+			}
+			grpcClientPermissions, err = loadGrpcClientPermissions()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if grpcClientPermissions != nil && (tlsConfig == nil || os.Getenv("GRPC_UPLOADS_TLS_CLIENT_CA") == "") {
+				// Client identities come from verified client certificates
+				log.Fatalln("GRPC_UPLOADS_CLIENT_PERMISSIONS requires GRPC_UPLOADS_TLS_CERT, GRPC_UPLOADS_TLS_KEY, and GRPC_UPLOADS_TLS_CLIENT_CA to be set")
+			}
+			s := grpc.NewServer(serverOpts...)
 			reflection.Register(s)
+			channelzService.RegisterChannelzServiceToServer(s)
+			healthServer := health.NewServer()
+			healthpb.RegisterHealthServer(s, healthServer)
+			go runGrpcHealthChecks(healthServer)
 			grpcUploads.RegisterUploadsServer(s, grpcUploadsServer{})
 			if err := s.Serve(lis); err != nil {
 				log.Fatalln(err)
@@ -141,14 +228,21 @@ func main() {
 	r := chi.NewRouter()
 	r.Use(cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
 	}).Handler)
-	r.Post("/{bucket:icons|emojis|stickers|attachments}", uploadFile)
-	r.Get("/{bucket:icons|emojis|stickers|attachments}/{id}", downloadFile)
-	r.Get("/{bucket:icons|emojis|stickers|attachments}/{id}/*", downloadFile)
+	bucketPattern := bucketRoutePattern()
+	r.Post("/"+bucketPattern, uploadFile)
+	r.Post("/"+bucketPattern+"/from-url", uploadFileFromUrl)
+	r.Post("/"+bucketPattern+"/from-hash", uploadFileFromHash)
+	r.Get("/"+bucketPattern+"/{id}", downloadFile)
+	r.Get("/"+bucketPattern+"/{id}/*", downloadFile)
+	r.Get("/"+bucketPattern+"/{id}/hls/*", downloadHLS)
 	r.Get("/data-exports/{id}", downloadDataExport)
+	r.Get("/me/usage", getMyUsage)
+	r.Get("/me/uploads", getMyUploads)
+	r.Delete("/me/uploads/{id}", deleteMyUpload)
 
 	// Send Sentry message
 	sentry.CaptureMessage("Starting uploads service")
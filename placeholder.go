@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Width and height of the thumbnail placeholders are computed from
+const placeholderSampleSize = 32
+
+// Number of BlurHash components on each axis
+const (
+	blurHashXComponents = 4
+	blurHashYComponents = 3
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Compute a BlurHash and dominant colour (as #rrggbb) of an image or the first frame of a video,
+// so clients have something to show while it's loading.
+func getPlaceholder(fileBytes []byte, mime string, crop *ImageCrop) (string, string, error) {
+	pixels, err := getThumbnailPixels(fileBytes, mime, crop)
+	if err != nil {
+		return "", "", err
+	}
+	if len(pixels) != placeholderSampleSize*placeholderSampleSize*3 {
+		return "", "", ErrUnsupportedFile
+	}
+
+	blurHash := encodeBlurHash(pixels, placeholderSampleSize, placeholderSampleSize, blurHashXComponents, blurHashYComponents)
+	return blurHash, getDominantColor(pixels), nil
+}
+
+// Get the 8-bit RGB pixels of a small thumbnail of an image or the first frame of a video
+func getThumbnailPixels(fileBytes []byte, mime string, crop *ImageCrop) ([]byte, error) {
+	size := fmt.Sprintf("%dx%d", placeholderSampleSize, placeholderSampleSize)
+
+	// Images
+	if format := imageMagickFormats[mime]; format != "" {
+		args := []string{format + ":-[0]", "-auto-orient"}
+		if crop != nil {
+			args = append(args, "-crop", fmt.Sprintf("%dx%d+%d+%d", crop.Width, crop.Height, crop.X, crop.Y), "+repage")
+		}
+		args = append(args, "-alpha", "remove", "-resize", size+"!", "-depth", "8", "rgb:-")
+		return runCommand(time.Second*30, fileBytes, "convert", args...)
+	}
+
+	// Videos
+	if strings.HasPrefix(mime, "video/") {
+		// FFmpeg needs to be able to seek in most video containers, so it can't read from stdin
+		tmpPath, err := createTempFile(fileBytes)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmpPath)
+
+		return runCommand(
+			time.Second*30,
+			nil,
+			"ffmpeg",
+			"-hide_banner",
+			"-loglevel", "error",
+			"-i", tmpPath,
+			"-frames:v", "1",
+			"-vf", "scale="+strings.Replace(size, "x", ":", 1),
+			"-f", "rawvideo",
+			"-pix_fmt", "rgb24",
+			"pipe:1",
+		)
+	}
+
+	return nil, ErrUnsupportedFile
+}
+
+// Encode 8-bit RGB pixels as a BlurHash (https://blurha.sh)
+func encodeBlurHash(pixels []byte, width int, height int, xComponents int, yComponents int) string {
+	// Get the factor of each component
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					offset := (y*width + x) * 3
+					for c := 0; c < 3; c++ {
+						factor[c] += basis * sRGBToLinear(pixels[offset+c])
+					}
+				}
+			}
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			for c := 0; c < 3; c++ {
+				factor[c] *= normalisation / float64(width*height)
+			}
+			factors = append(factors, factor)
+		}
+	}
+	dc, ac := factors[0], factors[1:]
+
+	// Size flag
+	hash := encodeBase83((xComponents-1)+(yComponents-1)*9, 1)
+
+	// Maximum AC value
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, factor := range ac {
+			for c := 0; c < 3; c++ {
+				actualMax = math.Max(actualMax, math.Abs(factor[c]))
+			}
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		hash += encodeBase83(quantisedMax, 1)
+	} else {
+		hash += encodeBase83(0, 1)
+	}
+
+	// DC value
+	hash += encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+
+	// AC values
+	for _, factor := range ac {
+		value := 0
+		for c := 0; c < 3; c++ {
+			quantised := math.Floor(signPow(factor[c]/maxValue, 0.5)*9 + 9.5)
+			value = value*19 + int(math.Max(0, math.Min(18, quantised)))
+		}
+		hash += encodeBase83(value, 2)
+	}
+
+	return hash
+}
+
+// Get the most common colour of 8-bit RGB pixels, as #rrggbb
+func getDominantColor(pixels []byte) string {
+	// Group similar colours together
+	type colorGroup struct {
+		count   int
+		r, g, b int
+	}
+	groups := make(map[int]*colorGroup)
+	var dominant *colorGroup
+	for i := 0; i+2 < len(pixels); i += 3 {
+		r, g, b := int(pixels[i]), int(pixels[i+1]), int(pixels[i+2])
+		key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+		group := groups[key]
+		if group == nil {
+			group = &colorGroup{}
+			groups[key] = group
+		}
+		group.count++
+		group.r += r
+		group.g += g
+		group.b += b
+		if dominant == nil || group.count > dominant.count {
+			dominant = group
+		}
+	}
+	if dominant == nil {
+		return ""
+	}
+
+	// Average the colours of the biggest group
+	return fmt.Sprintf("#%02x%02x%02x", dominant.r/dominant.count, dominant.g/dominant.count, dominant.b/dominant.count)
+}
+
+func encodeBase83(value int, length int) string {
+	var sb strings.Builder
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		sb.WriteByte(base83Chars[digit])
+	}
+	return sb.String()
+}
+
+func sRGBToLinear(value byte) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value float64, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+// 8-bit RGB pixels of an image that's red to the right, green to the bottom, and half blue
+func testGradientPixels(width int, height int) []byte {
+	pixels := make([]byte, 0, width*height*3)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixels = append(pixels, byte(x*255/(width-1)), byte(y*255/(height-1)), 128)
+		}
+	}
+	return pixels
+}
+
+func TestEncodeBlurHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		pixels      []byte
+		width       int
+		height      int
+		xComponents int
+		yComponents int
+		want        string
+	}{
+		{"black", make([]byte, 4*4*3), 4, 4, 4, 3, "L00000fQfQfQfQfQfQfQfQfQfQfQ"},
+		{"gradient", testGradientPixels(8, 8), 8, 8, 4, 3, "LyI5Yd3AfQxtuvRnfQnSfQfQfQfQ"},
+		{"gradient without AC components", testGradientPixels(8, 8), 8, 8, 1, 1, "00I5Yd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeBlurHash(tt.pixels, tt.width, tt.height, tt.xComponents, tt.yComponents)
+			if got != tt.want {
+				t.Errorf("encodeBlurHash() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDominantColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		pixels []byte
+		want   string
+	}{
+		{"no pixels", nil, ""},
+		{"one colour", []byte{255, 0, 0, 255, 0, 0}, "#ff0000"},
+		{"most common colour", []byte{0, 0, 255, 255, 0, 0, 0, 0, 255}, "#0000ff"},
+		{"similar colours averaged", []byte{16, 32, 48, 18, 34, 50, 200, 200, 200}, "#112131"},
+		{"trailing partial pixel", []byte{1, 2, 3, 4}, "#010203"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getDominantColor(tt.pixels); got != tt.want {
+				t.Errorf("getDominantColor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,39 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi/v5"
 	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Maximum number of files that can be uploaded in a single request
+const maxFilesPerUpload = 10
+
+// Maximum number of files from a single request that get processed at once
+const maxConcurrentUploads = 4
+
+type uploadResult struct {
+	File   *File  `json:"file"`
+	Error  string `json:"error,omitempty"`
+	status int
+}
+
 func uploadFile(w http.ResponseWriter, r *http.Request) {
 	// Get authed user
 	user, err := getUserByToken(r.Header.Get("Authorization"))
@@ -23,45 +43,224 @@ func uploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get file from request body
-	file, header, err := r.FormFile("file")
+	// Get files from request body
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	if len(headers) > maxFilesPerUpload {
+		http.Error(w, "Too many files", http.StatusBadRequest)
+		return
+	}
+
+	// Return the original response if this is a retry
+	var fingerprint string
+	if r.Header.Get("Idempotency-Key") != "" {
+		fingerprint, err = getUploadFingerprint(r.MultipartForm)
+		if err != nil {
+			sentry.CaptureException(err)
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+	}
+	idempotencyKey, cachedResp, err := beginIdempotentRequest(r, user.Username, fingerprint)
 	if err != nil {
-		if err != http.ErrMissingFile {
+		if err == ErrInvalidIdempotencyKey {
+			http.Error(w, "Invalid Idempotency-Key", http.StatusBadRequest)
+		} else if err == ErrIdempotencyKeyReused {
+			http.Error(w, "Idempotency-Key already used for a different request", http.StatusUnprocessableEntity)
+		} else if err == ErrRequestInProgress {
+			http.Error(w, "Request already in progress", http.StatusConflict)
+		} else {
 			sentry.CaptureException(err)
+			http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
 		}
-		http.Error(w, "Invalid form", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	if cachedResp != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(http.StatusOK)
+		w.Write(cachedResp)
+		return
+	}
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			if err := abortIdempotentRequest(idempotencyKey); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+	}()
 
-	// Make sure file doesn't exceeed maximum size
-	maxIconSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_ICON_SIZE_MIB"), 10, 32)
-	maxEmojiSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_EMOJI_SIZE_MIB"), 10, 32)
-	maxStickerSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_STICKER_SIZE_MIB"), 10, 32)
-	maxAttachmentSizeMib, _ := strconv.ParseInt(os.Getenv("MAX_ATTACHMENT_SIZE_MIB"), 10, 32)
-	if header.Size > map[string]int64{
-		"icons":       (maxIconSizeMib << 20),
-		"emojis":      (maxEmojiSizeMib << 20),
-		"stickers":    (maxStickerSizeMib << 20),
-		"attachments": (maxAttachmentSizeMib << 20),
-	}[chi.URLParam(r, "bucket")] {
-		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+	// Get crop
+	cropParams, err := parseCropParams(r)
+	if err != nil {
+		http.Error(w, "Invalid crop", http.StatusBadRequest)
 		return
 	}
 
+	// Process files
+	results := make([]uploadResult, len(headers))
+	sem := make(chan struct{}, maxConcurrentUploads)
+	var wg sync.WaitGroup
+	for i, header := range headers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, header *multipart.FileHeader) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = processUpload(chi.URLParam(r, "bucket"), header, user.Username, cropParams)
+		}(i, header)
+	}
+	wg.Wait()
+
+	// Return file details
+	// Single uploads get the file on its own, multiple uploads get a result for each file
+	var encoded []byte
+	if len(results) == 1 {
+		if results[0].Error != "" {
+			http.Error(w, results[0].Error, results[0].status)
+			return
+		}
+		encoded, err = json.Marshal(results[0].File)
+	} else {
+		encoded, err = json.Marshal(results)
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to send file details", http.StatusInternalServerError)
+		return
+	}
+	if err := finishIdempotentRequest(idempotencyKey, fingerprint, encoded); err != nil {
+		sentry.CaptureException(err)
+	} else {
+		succeeded = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
+
+func processUpload(bucket string, header *multipart.FileHeader, uploadedBy string, cropParams *CropParams) uploadResult {
+	// Make sure file doesn't exceeed maximum size
+	if header.Size > buckets[bucket].MaxSize() {
+		return uploadResult{Error: "File too large", status: http.StatusRequestEntityTooLarge}
+	}
+
 	// Read file
+	file, err := header.Open()
+	if err != nil {
+		sentry.CaptureException(err)
+		return uploadResult{Error: "Failed to read file", status: http.StatusInternalServerError}
+	}
+	defer file.Close()
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
 		sentry.CaptureException(err)
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return uploadResult{Error: "Failed to read file", status: http.StatusInternalServerError}
+	}
+
+	// Create file
+	f, err := CreateFile(bucket, fileBytes, header.Filename, header.Header.Get("Content-Type"), uploadedBy, "", cropParams)
+	if err != nil {
+		if err == ErrFileBlocked {
+			return uploadResult{Error: "File blocked", status: http.StatusForbidden}
+		} else if err == ErrUnsupportedFile {
+			return uploadResult{Error: "Unsupported file type", status: http.StatusUnsupportedMediaType}
+		} else if err == ErrInvalidCrop {
+			return uploadResult{Error: "Invalid crop", status: http.StatusBadRequest}
+		}
+		sentry.CaptureException(err)
+		return uploadResult{Error: "Failed to create file", status: http.StatusInternalServerError}
+	}
+
+	return uploadResult{File: &f, status: http.StatusOK}
+}
+
+// Get the crop rectangle (crop_x, crop_y, crop_width, crop_height) and
+// focal point (focal_x, focal_y) from the upload form, if there is one
+func parseCropParams(r *http.Request) (*CropParams, error) {
+	var params CropParams
+	var err error
+
+	if r.FormValue("crop_width") != "" || r.FormValue("crop_height") != "" {
+		params.HasRect = true
+		for _, field := range []struct {
+			name  string
+			value *int
+		}{
+			{"crop_x", &params.X},
+			{"crop_y", &params.Y},
+			{"crop_width", &params.Width},
+			{"crop_height", &params.Height},
+		} {
+			if *field.value, err = strconv.Atoi(r.FormValue(field.name)); err != nil {
+				return nil, ErrInvalidCrop
+			}
+		}
+	}
+
+	if r.FormValue("focal_x") != "" || r.FormValue("focal_y") != "" {
+		params.HasFocalPoint = true
+		if params.FocalX, err = strconv.ParseFloat(r.FormValue("focal_x"), 64); err != nil {
+			return nil, ErrInvalidCrop
+		}
+		if params.FocalY, err = strconv.ParseFloat(r.FormValue("focal_y"), 64); err != nil {
+			return nil, ErrInvalidCrop
+		}
+	}
+
+	if !params.HasRect && !params.HasFocalPoint {
+		return nil, nil
+	}
+	return &params, nil
+}
+
+func uploadFileFromUrl(w http.ResponseWriter, r *http.Request) {
+	// Get authed user
+	user, err := getUserByToken(r.Header.Get("Authorization"))
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	// Get URL from request body
+	var body struct {
+		Url string `json:"url"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8192)).Decode(&body); err != nil || body.Url == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// Fetch file
+	bucket := chi.URLParam(r, "bucket")
+	fileBytes, filename, mime, err := fetchRemoteFile(body.Url, buckets[bucket].MaxSize())
+	if err != nil {
+		if err == ErrInvalidUrl || err == ErrForbiddenTarget {
+			http.Error(w, "Invalid URL", http.StatusBadRequest)
+		} else if err == ErrFileTooLarge {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Failed to fetch file", http.StatusBadGateway)
+		}
 		return
 	}
 
 	// Create file
-	f, err := CreateFile(chi.URLParam(r, "bucket"), fileBytes, header.Filename, header.Header.Get("Content-Type"), user.Username)
+	f, err := CreateFile(bucket, fileBytes, filename, mime, user.Username, body.Url, nil)
 	if err != nil {
 		if err == ErrFileBlocked {
 			http.Error(w, "File blocked", http.StatusForbidden)
+		} else if err == ErrUnsupportedFile {
+			http.Error(w, "Unsupported file type", http.StatusUnsupportedMediaType)
 		} else {
 			sentry.CaptureException(err)
 			http.Error(w, "Failed to create file", http.StatusInternalServerError)
@@ -81,7 +280,55 @@ func uploadFile(w http.ResponseWriter, r *http.Request) {
 	w.Write(encoded)
 }
 
-func downloadFile(w http.ResponseWriter, r *http.Request) {
+func uploadFileFromHash(w http.ResponseWriter, r *http.Request) {
+	// Get authed user
+	user, err := getUserByToken(r.Header.Get("Authorization"))
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	// Get hash details from request body
+	var body struct {
+		Sha256   string `json:"sha256"`
+		Mime     string `json:"mime"`
+		Filename string `json:"filename"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8192)).Decode(&body); err != nil || len(body.Sha256) != 64 || body.Mime == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// Create file
+	f, err := CreateFileFromHash(chi.URLParam(r, "bucket"), strings.ToLower(body.Sha256), body.Mime, body.Filename, user.Username)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else if err == ErrFileBlocked {
+			http.Error(w, "File blocked", http.StatusForbidden)
+		} else {
+			sentry.CaptureException(err)
+			http.Error(w, "Failed to create file", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Return file details
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to send file details", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
+
+// Get the file a download request is for, making sure the requester is allowed to download it.
+// Writes the response and returns false if the request has already been handled.
+func getDownloadFile(w http.ResponseWriter, r *http.Request) (File, bool) {
 	// Get file
 	f, err := GetFile(chi.URLParam(r, "id"))
 	if err != nil || f.Bucket != chi.URLParam(r, "bucket") {
@@ -89,22 +336,96 @@ func downloadFile(w http.ResponseWriter, r *http.Request) {
 			sentry.CaptureException(err)
 		}
 		http.Error(w, "Not found", http.StatusNotFound)
-		return
+		return f, false
+	}
+
+	// Only the uploader can download files in private buckets
+	if buckets[f.Bucket].Private {
+		token := r.Header.Get("Authorization")
+		if token == "" {
+			token = r.URL.Query().Get("t")
+		}
+		user, err := getUserByToken(token)
+		if err != nil || user.Username != f.UploadedBy {
+			if err != nil && err != mongo.ErrNoDocuments {
+				sentry.CaptureException(err)
+			}
+			http.Error(w, "Not found", http.StatusNotFound)
+			return f, false
+		}
 	}
 
-	// Caching
-	if r.Header.Get("ETag") == f.Id || r.Header.Get("If-None-Match") == f.Id {
+	return f, true
+}
+
+// Respond with 304 Not Modified if the client already has the version of a download with the ETag
+func checkDownloadNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if r.Header.Get("ETag") == etag || r.Header.Get("If-None-Match") == etag {
 		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// Set the caching headers of a download
+func setDownloadCacheHeaders(w http.ResponseWriter, f File, etag string) {
+	w.Header().Set("ETag", etag)
+	if buckets[f.Bucket].Private {
+		w.Header().Set("Cache-Control", "private, max-age=31536000") // don't let the CDN cache private files
+	} else {
+		w.Header().Set("Cache-Control", "pbulic, max-age=31536000") // 1 year cache (files should never change)
+	}
+}
+
+func downloadFile(w http.ResponseWriter, r *http.Request) {
+	// Get file
+	f, ok := getDownloadFile(w, r)
+	if !ok {
 		return
 	}
 
 	// Get object
+	var err error
 	var obj *minio.Object
 	var objInfo *minio.ObjectInfo
-	if r.URL.Query().Has("preview") && f.Bucket == "attachments" {
-		obj, objInfo, err = f.GetPreviewObject()
+	getObject, objBucket, objKey, animated := f.GetObject, f.Bucket, f.Hash, f.Animated
+	var etagSuffix string
+	negotiate := buckets[f.Bucket].OptimizeSize > 0
+	if r.URL.Query().Has("static") && f.Animated {
+		getObject, objKey, animated, negotiate = f.GetStaticObject, f.staticKey(), false, true
+	} else if r.URL.Query().Has("preview") && buckets[f.Bucket].PreviewSize > 0 {
+		getObject, objBucket, negotiate = f.GetPreviewObject, previewsBucket, true
+	} else if f.Converted && !r.URL.Query().Has("download") {
+		// The original is only served for downloads
+		getObject, objKey, negotiate = f.GetDisplayObject, f.displayKey(), true
+	} else if r.URL.Query().Has("transcoded") {
+		// The rendition isn't served until it's ready, and nothing gets cached in its place
+		if f.Transcode == nil || f.Transcode.Status != "completed" {
+			w.Header().Set("Cache-Control", "no-store")
+			if f.Transcode != nil && (f.Transcode.Status == "pending" || f.Transcode.Status == "running") {
+				http.Error(w, "Transcoding", http.StatusAccepted)
+			} else {
+				http.Error(w, "Not found", http.StatusNotFound)
+			}
+			return
+		}
+		getObject, negotiate, etagSuffix = f.GetTranscodedObject, false, "-mp4"
+	}
+	var format string
+	etag := f.Id + etagSuffix
+	if negotiate && !r.URL.Query().Has("download") {
+		// Generated images get served in the best format the client supports
+		format = negotiateImageFormat(r.Header.Get("Accept"), animated, f.Mime)
+		etag += "-" + format
+		w.Header().Set("Vary", "Accept")
+	}
+	if checkDownloadNotModified(w, r, etag) {
+		return
+	}
+	if format != "" {
+		obj, objInfo, err = f.getObjectAs(format, objBucket, objKey, getObject)
 	} else {
-		obj, objInfo, err = f.GetObject()
+		obj, objInfo, err = getObject()
 	}
 	if err != nil {
 		sentry.CaptureException(err)
@@ -117,8 +438,7 @@ func downloadFile(w http.ResponseWriter, r *http.Request) {
 	// Set response headers
 	w.Header().Set("Content-Type", objInfo.ContentType)
 	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
-	w.Header().Set("ETag", f.Id)
-	w.Header().Set("Cache-Control", "pbulic, max-age=31536000") // 1 year cache (files should never change)
+	setDownloadCacheHeaders(w, f, etag)
 	filename := chi.URLParam(r, "*")
 	if filename == "" {
 		filename = f.Id
@@ -139,8 +459,18 @@ func downloadFile(w http.ResponseWriter, r *http.Request) {
 }
 
 func downloadDataExport(w http.ResponseWriter, r *http.Request) {
-	// Get object info
-	objInfo, err := s3Clients[s3RegionOrder[0]].StatObject(ctx, "data-exports", chi.URLParam(r, "id"), minio.StatObjectOptions{})
+	id := chi.URLParam(r, "id")
+
+	// Get object info, falling back to other regions if the export isn't local
+	var region string
+	var objInfo minio.ObjectInfo
+	var err error
+	for _, region = range s3RegionOrder {
+		objInfo, err = s3Clients[region].StatObject(ctx, "data-exports", id, minio.StatObjectOptions{})
+		if err == nil {
+			break
+		}
+	}
 	if err != nil {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
@@ -149,30 +479,257 @@ func downloadDataExport(w http.ResponseWriter, r *http.Request) {
 	// Get & check token details
 	user, err := getUserByToken(r.URL.Query().Get("t"))
 	if err != nil || user.Username != objInfo.UserMetadata["User-Id"] {
-		sentry.CaptureException(err)
+		if err != nil && err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
+		}
+		logDataExportAccess(r, id, user.Username, region, false)
 		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
 		return
 	}
 
 	// Get object
-	obj, err := s3Clients[s3RegionOrder[0]].GetObject(ctx, "data-exports", chi.URLParam(r, "id"), minio.GetObjectOptions{})
+	obj, err := s3Clients[region].GetObject(ctx, "data-exports", id, minio.GetObjectOptions{})
 	if err != nil {
 		sentry.CaptureException(err)
 		http.Error(w, "Failed to get object", http.StatusInternalServerError)
 		return
 	}
+	defer obj.Close()
+	logDataExportAccess(r, id, user.Username, region, true)
 
 	// Set response headers
+	// ETag lets clients resume interrupted downloads with Range and If-Range
 	w.Header().Set("Content-Type", objInfo.ContentType)
-	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
-	w.Header().Set("Cache-Control", "none") // do not cache
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, objInfo.ETag))
+	w.Header().Set("Cache-Control", "private, no-store") // do not cache
 	w.Header().Set("Content-Disposition", "attachment; filename=meower_export.zip")
 
-	// Copy the object data into the response body
-	_, err = io.Copy(w, obj)
+	// Send the object, handles Range, If-Range, and conditional requests
+	http.ServeContent(w, r, "meower_export.zip", objInfo.LastModified, obj)
+}
+
+// Record an attempt to download a data export for auditing
+func logDataExportAccess(r *http.Request, exportId string, username string, region string, allowed bool) {
+	ip := r.Header.Get("CF-Connecting-IP")
+	if ip == "" {
+		ip = r.RemoteAddr
+	}
+	_, err := db.Collection("data_export_access_log").InsertOne(context.TODO(), bson.M{
+		"export_id":  exportId,
+		"user":       username,
+		"region":     region,
+		"allowed":    allowed,
+		"ip":         ip,
+		"user_agent": r.UserAgent(),
+		"range":      r.Header.Get("Range"),
+		"time":       time.Now().Unix(),
+	})
 	if err != nil {
 		sentry.CaptureException(err)
-		http.Error(w, "Failed to send object", http.StatusInternalServerError)
+	}
+}
+
+func getMyUsage(w http.ResponseWriter, r *http.Request) {
+	// Get authed user
+	user, err := getUserByToken(r.Header.Get("Authorization"))
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
+		}
+		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	// Get usage
+	buckets, err := GetStorageUsage(user.Username)
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to get usage", http.StatusInternalServerError)
+		return
+	}
+	var totalSize int64
+	for _, bucket := range buckets {
+		totalSize += bucket.Size
+	}
+
+	// Return usage
+	encoded, err := json.Marshal(map[string]any{
+		"buckets":    buckets,
+		"total_size": totalSize,
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to send usage", http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
+
+// What users get to see about each of their uploads
+type myUpload struct {
+	Id         string `json:"id"`
+	Bucket     string `json:"bucket"`
+	Mime       string `json:"mime"`
+	Filename   string `json:"filename,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	UploadedAt int64  `json:"uploaded_at"`
+	Claimed    bool   `json:"claimed"`
+}
+
+// Encode the position after a file in the upload history into an opaque cursor
+func encodeUploadsCursor(uploadedAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(uploadedAt, 10) + ":" + id))
+}
+
+// Decode a cursor from encodeUploadsCursor
+func decodeUploadsCursor(cursor string) (int64, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", err
+	}
+	uploadedAtStr, id, found := strings.Cut(string(decoded), ":")
+	if !found || id == "" {
+		return 0, "", errors.New("invalid cursor")
+	}
+	uploadedAt, err := strconv.ParseInt(uploadedAtStr, 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return uploadedAt, id, nil
+}
+
+func getMyUploads(w http.ResponseWriter, r *http.Request) {
+	// Get authed user
+	user, err := getUserByToken(r.Header.Get("Authorization"))
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
+		}
+		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	// Create query
+	query := bson.M{"uploaded_by": user.Username}
+	if bucket := r.URL.Query().Get("bucket"); bucket != "" {
+		query["bucket"] = bucket
+	}
+	if claimed := r.URL.Query().Get("claimed"); claimed != "" {
+		query["references.0"] = bson.M{"$exists": claimed == "true" || claimed == "1"}
+	}
+
+	// Continue from cursor (the position of the last file on the previous page,
+	// so it still works if that file has been deleted)
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		cursorUploadedAt, cursorId, err := decodeUploadsCursor(cursor)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query["$or"] = bson.A{
+			bson.M{"uploaded_at": bson.M{"$lt": cursorUploadedAt}},
+			bson.M{"uploaded_at": cursorUploadedAt, "_id": bson.M{"$lt": cursorId}},
+		}
+	}
+
+	// Get page limit
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	// Get files
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "uploaded_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetLimit(limit)
+	cur, err := db.Collection("files").Find(context.TODO(), query, opts)
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to get files", http.StatusInternalServerError)
+		return
+	}
+	files := []File{}
+	if err := cur.All(context.TODO(), &files); err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to get files", http.StatusInternalServerError)
+		return
+	}
+
+	// Get next cursor
+	var nextCursor string
+	if int64(len(files)) == limit {
+		lastFile := files[len(files)-1]
+		nextCursor = encodeUploadsCursor(lastFile.UploadedAt, lastFile.Id)
+	}
+
+	// Return files
+	uploads := make([]myUpload, len(files))
+	for i, f := range files {
+		uploads[i] = myUpload{
+			Id:         f.Id,
+			Bucket:     f.Bucket,
+			Mime:       f.Mime,
+			Filename:   f.Filename,
+			Width:      f.Width,
+			Height:     f.Height,
+			Size:       f.Size,
+			UploadedAt: f.UploadedAt,
+			Claimed:    len(f.References) > 0,
+		}
+	}
+	encoded, err := json.Marshal(map[string]any{
+		"files":       uploads,
+		"next_cursor": nextCursor,
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to send files", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
+
+func deleteMyUpload(w http.ResponseWriter, r *http.Request) {
+	// Get authed user
+	user, err := getUserByToken(r.Header.Get("Authorization"))
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
+		}
+		http.Error(w, "Invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	// Get file
+	f, err := GetFile(chi.URLParam(r, "id"))
+	if err != nil || f.UploadedBy != user.Username {
+		if err != nil && err != mongo.ErrNoDocuments {
+			sentry.CaptureException(err)
+		}
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// Delete file, as long as nothing is using it
+	deleted, err := f.DeleteIfUnreferenced()
+	if err != nil {
+		sentry.CaptureException(err)
+		http.Error(w, "Failed to delete file", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "File is in use", http.StatusConflict)
+		return
+	}
+
+	// Purge from CF cache
+	go purgeFilesFromCDN([]File{f})
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestUploadsCursor(t *testing.T) {
+	tests := []struct {
+		uploadedAt int64
+		id         string
+	}{
+		{1700000000, "abc123"},
+		{0, "a"},
+		{-1, "with:colon"},
+		{1700000000, "ID with spaces/and+symbols"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			cursor := encodeUploadsCursor(tt.uploadedAt, tt.id)
+			uploadedAt, id, err := decodeUploadsCursor(cursor)
+			if err != nil {
+				t.Fatalf("decodeUploadsCursor(%q) returned error %v", cursor, err)
+			}
+			if uploadedAt != tt.uploadedAt || id != tt.id {
+				t.Errorf("decodeUploadsCursor(%q) = %d, %q, want %d, %q", cursor, uploadedAt, id, tt.uploadedAt, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidUploadsCursor(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not base64!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1:ab"))},
+		{"file ID", "abc123"},
+		{"no separator", encode("1700000000")},
+		{"no ID", encode("1700000000:")},
+		{"no timestamp", encode(":abc123")},
+		{"timestamp isn't a number", encode("yesterday:abc123")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeUploadsCursor(tt.cursor); err == nil {
+				t.Errorf("decodeUploadsCursor(%q) didn't return an error", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Redis list video transcoding jobs are queued in
+const transcodeQueue = "transcode_jobs"
+
+// Limits for transcoded videos, anything longer gets cut off
+const (
+	maxTranscodeDuration  = time.Minute * 10
+	maxTranscodeDimension = 1920
+	transcodeTimeout      = time.Minute * 15
+)
+
+// Status of the web-safe H.264/AAC MP4 rendition of a video.
+// Shared by every file in the bucket with the same hash.
+type TranscodeStatus struct {
+	Status      string  `bson:"status" json:"status"` // pending, running, completed, failed
+	Region      string  `bson:"region,omitempty" json:"region,omitempty"`
+	Size        int64   `bson:"size,omitempty" json:"size,omitempty"`
+	Width       int     `bson:"width,omitempty" json:"width,omitempty"`
+	Height      int     `bson:"height,omitempty" json:"height,omitempty"`
+	Duration    float64 `bson:"duration,omitempty" json:"duration,omitempty"` // seconds
+	HLS         bool    `bson:"hls,omitempty" json:"hls,omitempty"`           // whether there's an HLS playlist at /{bucket}/{id}/hls/playlist.m3u8
+	HLSSegments int     `bson:"hls_segments,omitempty" json:"hls_segments,omitempty"`
+	UpdatedAt   int64   `bson:"updated_at" json:"updated_at"`
+}
+
+type transcodeJob struct {
+	Bucket       string `json:"bucket"`
+	Hash         string `json:"hash"`
+	UploadRegion string `json:"upload_region"`
+}
+
+// Get the transcode status of another file in the bucket with the same hash, if there is one
+// (renditions are stored next to the original, so files in other buckets don't count)
+func getTranscodeStatus(bucket string, hash string) (*TranscodeStatus, error) {
+	var f File
+	err := db.Collection("files").FindOne(context.TODO(), bson.M{
+		"bucket":    bucket,
+		"hash":      hash,
+		"transcode": bson.M{"$exists": true},
+	}).Decode(&f)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return f.Transcode, err
+}
+
+// Set the transcode status of every file in a bucket with a hash
+func setTranscodeStatus(bucket string, hash string, status *TranscodeStatus) error {
+	status.UpdatedAt = time.Now().Unix()
+	_, err := db.Collection("files").UpdateMany(
+		context.TODO(),
+		bson.M{"bucket": bucket, "hash": hash},
+		bson.M{"$set": bson.M{"transcode": status}},
+	)
+	return err
+}
+
+// Add a video to the transcoding queue
+func queueTranscode(bucket string, hash string, uploadRegion string) error {
+	encoded, err := json.Marshal(transcodeJob{
+		Bucket:       bucket,
+		Hash:         hash,
+		UploadRegion: uploadRegion,
+	})
+	if err != nil {
+		return err
+	}
+	return rdb.LPush(ctx, transcodeQueue, encoded).Err()
+}
+
+// Queue videos again if their transcoding job has been lost (e.g. from a worker restarting)
+func requeueStaleTranscodes() error {
+	cur, err := db.Collection("files").Find(context.TODO(), bson.M{
+		"transcode.status":     bson.M{"$in": []string{"pending", "running"}},
+		"transcode.updated_at": bson.M{"$lt": time.Now().Add(-(transcodeTimeout * 2)).Unix()},
+	})
+	if err != nil {
+		return err
+	}
+	var files []File
+	if err := cur.All(context.TODO(), &files); err != nil {
+		return err
+	}
+
+	queuedObjects := make(map[string]bool)
+	for _, f := range files {
+		if queuedObjects[f.Bucket+"/"+f.Hash] {
+			continue
+		}
+		queuedObjects[f.Bucket+"/"+f.Hash] = true
+
+		if err := setTranscodeStatus(f.Bucket, f.Hash, &TranscodeStatus{Status: "pending"}); err != nil {
+			return err
+		}
+		if err := queueTranscode(f.Bucket, f.Hash, f.UploadRegion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Take jobs off the transcoding queue forever
+func runTranscodeWorker() {
+	for {
+		result, err := rdb.BRPop(ctx, 0, transcodeQueue).Result()
+		if err != nil {
+			sentry.CaptureException(err)
+			time.Sleep(time.Second * 5)
+			continue
+		}
+
+		var job transcodeJob
+		if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if err := job.run(); err != nil {
+			sentry.CaptureException(err)
+			if err := setTranscodeStatus(job.Bucket, job.Hash, &TranscodeStatus{Status: "failed"}); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+	}
+}
+
+func (job *transcodeJob) run() error {
+	// Make sure the video is still needed
+	referencedCount, err := db.Collection("files").CountDocuments(context.TODO(), bson.M{"bucket": job.Bucket, "hash": job.Hash})
+	if err != nil {
+		return err
+	}
+	if referencedCount == 0 {
+		return nil
+	}
+	if err := setTranscodeStatus(job.Bucket, job.Hash, &TranscodeStatus{Status: "running"}); err != nil {
+		return err
+	}
+
+	// Get source object
+	f := File{
+		Bucket:       job.Bucket,
+		Hash:         job.Hash,
+		UploadRegion: job.UploadRegion,
+	}
+	obj, _, err := f.GetObject()
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	// FFmpeg needs to be able to seek in most video containers, so it can't use pipes
+	srcFile, err := os.CreateTemp("", "meower-uploads-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(srcFile.Name())
+	if _, err := io.Copy(srcFile, obj); err != nil {
+		srcFile.Close()
+		return err
+	}
+	if err := srcFile.Close(); err != nil {
+		return err
+	}
+	dstPath := srcFile.Name() + ".mp4"
+	defer os.Remove(dstPath)
+
+	// Transcode video
+	if _, err := runCommand(
+		transcodeTimeout,
+		nil,
+		"ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", srcFile.Name(),
+		"-t", strconv.FormatFloat(maxTranscodeDuration.Seconds(), 'f', -1, 64),
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+		"-vf", "scale="+
+			"'if(gt(iw,ih),min("+strconv.Itoa(maxTranscodeDimension)+",iw),-2)':"+
+			"'if(gt(iw,ih),-2,min("+strconv.Itoa(maxTranscodeDimension)+",ih))'",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "23",
+		"-profile:v", "high",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-ac", "2",
+		"-movflags", "+faststart",
+		dstPath,
+	); err != nil {
+		return err
+	}
+
+	// Probe rendition
+	probeBytes, err := runCommand(
+		time.Second*30,
+		nil,
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-select_streams", "v:0",
+		dstPath,
+	)
+	if err != nil {
+		return err
+	}
+	var probe ffprobeOutput
+	if err := json.Unmarshal(probeBytes, &probe); err != nil {
+		return err
+	}
+	status := &TranscodeStatus{
+		Status: "completed",
+		Region: s3RegionOrder[0],
+	}
+	if len(probe.Streams) > 0 {
+		status.Width, status.Height = probe.Streams[0].Width, probe.Streams[0].Height
+	}
+	status.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+
+	// Put rendition
+	objInfo, err := s3Clients[s3RegionOrder[0]].FPutObject(
+		ctx,
+		f.Bucket,
+		f.transcodedKey(),
+		dstPath,
+		minio.PutObjectOptions{
+			ContentType: "video/mp4",
+		},
+	)
+	if err != nil {
+		return err
+	}
+	status.Size = objInfo.Size
+
+	// Package long videos for HLS
+	if config := buckets[f.Bucket]; config != nil && config.HLSMinDuration > 0 && status.Duration >= float64(config.HLSMinDuration) {
+		status.HLSSegments, err = packageHLS(f, dstPath)
+		if err != nil {
+			return err
+		}
+		status.HLS = true
+	}
+
+	return setTranscodeStatus(job.Bucket, job.Hash, status)
+}
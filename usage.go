@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StorageUsage struct {
+	Bucket    string `bson:"bucket" json:"bucket"`
+	Size      int64  `bson:"size" json:"size"`
+	Files     int64  `bson:"files" json:"files"`
+	UpdatedAt int64  `bson:"updated_at" json:"updated_at"`
+}
+
+// Get a user's storage usage for each bucket, as of the last rollup
+func GetStorageUsage(userId string) ([]StorageUsage, error) {
+	cur, err := db.Collection("storage_usage").Find(
+		context.TODO(),
+		bson.M{"user": userId},
+		options.Find().SetSort(bson.M{"bucket": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []StorageUsage{}
+	err = cur.All(context.TODO(), &buckets)
+	return buckets, err
+}
+
+// Recalculate the per-user, per-bucket storage usage aggregates
+func rollupStorageUsage() error {
+	if err := backfillFileSizes(); err != nil {
+		return err
+	}
+
+	startedAt := time.Now().Unix()
+	cur, err := db.Collection("files").Aggregate(context.TODO(), mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"user": "$uploaded_by", "bucket": "$bucket"},
+			"size":  bson.M{"$sum": "$size"},
+			"files": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"user":       "$_id.user",
+			"bucket":     "$_id.bucket",
+			"updated_at": startedAt,
+		}}},
+		{{Key: "$merge", Value: bson.M{"into": "storage_usage", "whenMatched": "replace"}}},
+	})
+	if err != nil {
+		return err
+	}
+	cur.Close(context.TODO())
+
+	// Remove aggregates for users/buckets that no longer have any files
+	_, err = db.Collection("storage_usage").DeleteMany(context.TODO(), bson.M{"updated_at": bson.M{"$lt": startedAt}})
+	return err
+}
+
+// Set the size of files that were uploaded before sizes were stored.
+// Files whose object can't be found get a size of 0 and are marked with size_unknown,
+// so they don't get checked again on every rollup.
+func backfillFileSizes() error {
+	cur, err := db.Collection("files").Find(
+		context.TODO(),
+		bson.M{"size": bson.M{"$exists": false}},
+		options.Find().SetLimit(1000),
+	)
+	if err != nil {
+		return err
+	}
+	var files []File
+	if err := cur.All(context.TODO(), &files); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		update := bson.M{"size": int64(0), "size_unknown": true}
+		for _, region := range []string{s3RegionOrder[0], f.UploadRegion} {
+			s3Client, ok := s3Clients[region]
+			if !ok {
+				continue
+			}
+			objInfo, err := s3Client.StatObject(ctx, f.Bucket, f.Hash, minio.StatObjectOptions{})
+			if err != nil {
+				continue
+			}
+			update = bson.M{"size": objInfo.Size}
+			break
+		}
+		if _, err := db.Collection("files").UpdateOne(
+			context.TODO(),
+			bson.M{"_id": f.Id},
+			bson.M{"$set": update},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/discord/lilliput"
+	"github.com/getsentry/sentry-go"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -21,12 +28,36 @@ var SupportedImages = map[string]bool{
 	"image/gif":  true,
 }
 
+// ImageMagick format names for supported images
+var imageMagickFormats = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/webp": "webp",
+	"image/gif":  "gif",
+}
+
+var ReferenceTypes = map[string]bool{
+	"post":       true,
+	"chat_icon":  true,
+	"profile":    true,
+	"emoji_pack": true,
+}
+
 var (
 	ErrUnsupportedFile    = errors.New("unsupported file")
 	ErrFileBlocked        = errors.New("file blocked")
 	ErrFileAlreadyClaimed = errors.New("file already claimed")
 	ErrMismatchedBucket   = errors.New("mismatched bucket")
 	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrInvalidReference   = errors.New("invalid reference")
+	ErrInvalidCrop        = errors.New("invalid crop")
+
+	ErrDataExportCancelled = errors.New("data export cancelled")
+
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrRequestInProgress     = errors.New("request in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused")
 )
 
 func generateId() (string, error) {
@@ -50,17 +81,30 @@ func cleanFilename(filename string) string {
 	return re.ReplaceAllString(filename, "_")
 }
 
-func optimizeImage(imageBytes []byte, mime string, maxSize int) ([]byte, string, error) {
+// Resize an image to fit within maxSize, and convert it to format (webp, png, or jpeg).
+// Animated images are always converted to animated WebPs so they stay animated.
+func optimizeImage(imageBytes []byte, mime string, maxSize int, format string) ([]byte, string, error) {
+	if !SupportedImages[mime] {
+		return nil, "", ErrUnsupportedFile
+	}
+	if isAnimatedImage(imageBytes, mime) {
+		newImageBytes, err := transcodeAnimatedImage(imageBytes, mime, maxSize)
+		return newImageBytes, "image/webp", err
+	}
+	return optimizeStaticImage(imageBytes, mime, maxSize, format)
+}
+
+// Resize the first frame of an image to fit within maxSize (or keep its size if maxSize is 0),
+// and convert it to format (webp, png, or jpeg).
+func optimizeStaticImage(imageBytes []byte, mime string, maxSize int, format string) ([]byte, string, error) {
 	// Get file extension
-	fileExt := map[string]string{
-		"image/png":  ".webp",
-		"image/jpeg": ".webp",
-		"image/webp": ".webp",
-		"image/gif":  ".gif",
-	}[mime]
-	if fileExt == "" {
+	if !SupportedImages[mime] {
 		return nil, "", ErrUnsupportedFile
 	}
+	if format == "" {
+		format = "webp"
+	}
+	fileExt := "." + format
 
 	// Get lilliput decoder
 	lilliputDecoder, err := lilliput.NewDecoder(imageBytes)
@@ -75,6 +119,10 @@ func optimizeImage(imageBytes []byte, mime string, maxSize int) ([]byte, string,
 		return nil, "", err
 	}
 
+	if maxSize <= 0 {
+		maxSize = max(originalWidth, originalHeight)
+	}
+
 	// Calculate aspect ratio of the original image
 	aspectRatio := float64(originalWidth) / float64(originalHeight)
 
@@ -91,10 +139,11 @@ func optimizeImage(imageBytes []byte, mime string, maxSize int) ([]byte, string,
 
 	// Create lilliput options
 	lilliputOpts := lilliput.ImageOptions{
-		FileType:     fileExt,
-		Width:        newWidth,
-		Height:       newHeight,
-		ResizeMethod: lilliput.ImageOpsResize,
+		FileType:        fileExt,
+		Width:           newWidth,
+		Height:          newHeight,
+		ResizeMethod:    lilliput.ImageOpsResize,
+		MaxEncodeFrames: 1,
 	}
 
 	// Create ops
@@ -106,8 +155,12 @@ func optimizeImage(imageBytes []byte, mime string, maxSize int) ([]byte, string,
 	newImageBytes, err := lilliputOps.Transform(lilliputDecoder, &lilliputOpts, make([]byte, 0, 10<<20))
 	newMime := map[string]string{
 		".webp": "image/webp",
-		".gif":  "image/gif",
+		".png":  "image/png",
+		".jpeg": "image/jpeg",
 	}[fileExt]
+	if newMime == "" {
+		return nil, "", ErrUnsupportedFile
+	}
 	return newImageBytes, newMime, err
 }
 
@@ -130,11 +183,11 @@ func getMediaDimensions(lilliputDecoder lilliput.Decoder) (int, int, error) {
 	}
 }
 
-// Delete unclaimed files that are more than 30 minutes old
+// Delete unreferenced files that are more than 30 minutes old
 func cleanupFiles() error {
 	cur, err := db.Collection("files").Find(context.TODO(), bson.M{
-		"claimed":     false,
-		"uploaded_at": bson.M{"$lt": time.Now().Unix() - 1800},
+		"references.0": bson.M{"$exists": false},
+		"uploaded_at":  bson.M{"$lt": time.Now().Unix() - 1800},
 	})
 	if err != nil {
 		return err
@@ -146,7 +199,19 @@ func cleanupFiles() error {
 	}
 
 	for _, file := range files {
-		if err := file.Delete(); err != nil {
+		// Skip the file if it got referenced since we fetched it
+		if _, err := file.DeleteIfUnreferenced(); err != nil {
+			return err
+		}
+	}
+
+	// Delete files that are older than their bucket's retention period (even if they're still referenced)
+	for _, name := range bucketOrder {
+		config := buckets[name]
+		if config.RetentionDays <= 0 {
+			continue
+		}
+		if err := deleteExpiredFiles(config); err != nil {
 			return err
 		}
 	}
@@ -154,6 +219,98 @@ func cleanupFiles() error {
 	return nil
 }
 
+// Delete the files of a bucket that are older than its retention period, a batch at a time.
+// Files that fail to get deleted are logged and left for the next sweep.
+func deleteExpiredFiles(config *BucketConfig) error {
+	cur, err := db.Collection("files").Find(context.TODO(), bson.M{
+		"bucket":      config.Name,
+		"uploaded_at": bson.M{"$lt": time.Now().Unix() - int64(config.RetentionDays*86400)},
+	}, options.Find().SetBatchSize(100))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+
+	var deletedFiles []File
+	for cur.Next(context.TODO()) {
+		var file File
+		if err := cur.Decode(&file); err != nil {
+			log.Println(err)
+			sentry.CaptureException(err)
+			continue
+		}
+		if err := file.Delete(); err != nil {
+			log.Println(err)
+			sentry.CaptureException(err)
+			continue
+		}
+
+		deletedFiles = append(deletedFiles, file)
+		if len(deletedFiles) == 100 {
+			go purgeFilesFromCDN(deletedFiles)
+			deletedFiles = nil
+		}
+	}
+	if len(deletedFiles) > 0 {
+		go purgeFilesFromCDN(deletedFiles)
+	}
+
+	return cur.Err()
+}
+
+// Convert files from the old claimed flag into references, with the ID they were claimed by (or "unknown").
+// The reference type comes from the bucket, so ClearFiles keeps emojis and chat icons other chats use:
+// emojis are emoji pack references, icons are profile references if they were claimed by their uploader
+// and chat icon references otherwise, and attachments are post references.
+// Files in other buckets get a legacy reference, which nothing releases other than ClearFiles.
+func migrateClaimedFiles() error {
+	// Claimed files
+	claimedBy := bson.M{"$cond": bson.A{
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$claimed_by"}, "string"}},
+			bson.M{"$ne": bson.A{"$claimed_by", ""}},
+		}},
+		"$claimed_by",
+		"unknown",
+	}}
+	if _, err := db.Collection("files").UpdateMany(
+		context.TODO(),
+		bson.M{"claimed": true},
+		bson.A{
+			bson.M{"$set": bson.M{"references": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$references", bson.A{}}},
+				bson.A{bson.M{
+					"type": bson.M{"$switch": bson.M{
+						"branches": bson.A{
+							bson.M{"case": bson.M{"$eq": bson.A{"$bucket", "emojis"}}, "then": "emoji_pack"},
+							bson.M{"case": bson.M{"$and": bson.A{
+								bson.M{"$eq": bson.A{"$bucket", "icons"}},
+								bson.M{"$eq": bson.A{"$claimed_by", "$uploaded_by"}},
+							}}, "then": "profile"},
+							bson.M{"case": bson.M{"$eq": bson.A{"$bucket", "icons"}}, "then": "chat_icon"},
+							bson.M{"case": bson.M{"$eq": bson.A{"$bucket", "attachments"}}, "then": "post"},
+						},
+						"default": "legacy",
+					}},
+					"id":       claimedBy,
+					"added_at": time.Now().Unix(),
+				}},
+			}}}},
+			bson.M{"$unset": bson.A{"claimed", "claimed_by", "claimed_at"}},
+		},
+	); err != nil {
+		return err
+	}
+
+	// Unclaimed files
+	_, err := db.Collection("files").UpdateMany(
+		context.TODO(),
+		bson.M{"claimed": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"claimed": "", "claimed_by": "", "claimed_at": ""}},
+	)
+	return err
+}
+
 // Get the block status of a file by its hash.
 // Returns whether it's blocked.
 func getBlockStatus(hashHex string) (bool, error) {
@@ -162,3 +319,77 @@ func getBlockStatus(hashHex string) (bool, error) {
 	count, err := db.Collection("blocked_files").CountDocuments(context.TODO(), bson.M{"_id": hashHex}, opts)
 	return count > 0, err
 }
+
+// Purge files from the Cloudflare cache, if Cloudflare is configured
+func purgeFilesFromCDN(files []File) {
+	// Get token, zone ID, and URL
+	token := os.Getenv("CF_TOKEN")
+	zoneId := os.Getenv("CF_ZONE_ID")
+	url := os.Getenv("CF_URL")
+	if token == "" || zoneId == "" || url == "" {
+		return
+	}
+
+	// Create file URLs
+	fileUrls := []string{}
+	for _, f := range files {
+		if config := buckets[f.Bucket]; config != nil && config.Private {
+			continue // private files are never cached
+		}
+		fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id))
+		if f.Animated {
+			fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "?static"))
+		}
+		if f.Transcode != nil {
+			fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "?transcoded"))
+		}
+		fileUrls = append(fileUrls, hlsFileUrls(url, f)...)
+		if f.Filename != "" {
+			fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename))
+			fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?download"))
+			if f.Animated {
+				fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?static"))
+			}
+			if f.Transcode != nil {
+				fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?transcoded"))
+			}
+			if f.hasPreviews() {
+				fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?preview"))
+				fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?preview&download"))
+				fileUrls = append(fileUrls, fmt.Sprint(url, "/", f.Bucket, "/", f.Id, "/", f.Filename, "?download&preview"))
+			}
+		}
+	}
+
+	// Cloudflare only allows purging 30 URLs per request
+	for len(fileUrls) > 0 {
+		batch := fileUrls[:min(len(fileUrls), 30)]
+		fileUrls = fileUrls[len(batch):]
+
+		// Create body
+		jsonBody, err := json.Marshal(map[string][]string{
+			"files": batch,
+		})
+		if err != nil {
+			sentry.CaptureException(err)
+			return
+		}
+
+		// Create request
+		apiUrl := fmt.Sprint("https://api.cloudflare.com/client/v4/zones/", zoneId, "/purge_cache")
+		req, err := http.NewRequest(http.MethodPost, apiUrl, bytes.NewReader(jsonBody))
+		if err != nil {
+			sentry.CaptureException(err)
+			return
+		}
+		req.Header.Add("Authorization", fmt.Sprint("Bearer ", token))
+
+		// Send request
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			sentry.CaptureException(err)
+			return
+		}
+		resp.Body.Close()
+	}
+}